@@ -0,0 +1,56 @@
+package auth
+
+import (
+	"context"
+	"time"
+)
+
+type contextKey struct{}
+
+type Principal struct {
+	UserID         string
+	Roles          []string
+	Permissions    []string
+	EmailVerified  bool
+	TokenID        string
+	TokenExpiresAt time.Time
+	SessionID      string
+	APIKeyID       string
+	Scopes         []string
+}
+
+func (p *Principal) HasRole(role string) bool {
+	for _, r := range p.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Principal) HasPermission(permission string) bool {
+	for _, granted := range p.Permissions {
+		if granted == permission {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Principal) HasScope(scope string) bool {
+	for _, granted := range p.Scopes {
+		if granted == scope {
+			return true
+		}
+	}
+	return false
+}
+
+func WithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, contextKey{}, principal)
+}
+
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	principal, ok := ctx.Value(contextKey{}).(*Principal)
+	return principal, ok
+}
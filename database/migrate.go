@@ -0,0 +1,121 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"log/slog"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	MIGRATION_LOCK         = "schema_migrations"
+	MIGRATION_LOCK_TIMEOUT = 5 * time.Minute
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+type Migration struct {
+	Version    int
+	Name       string
+	Statements []string
+}
+
+func Migrations() ([]Migration, error) {
+	entries, err := migrationFiles.ReadDir("migrations")
+	if err != nil {
+		return nil, err
+	}
+	var migrations []Migration
+	for _, entry := range entries {
+		name := entry.Name()
+		prefix, _, ok := strings.Cut(name, "_")
+		if !ok {
+			return nil, fmt.Errorf("migration %s has no version prefix", name)
+		}
+		version, err := strconv.Atoi(prefix)
+		if err != nil {
+			return nil, fmt.Errorf("migration %s has an invalid version prefix", name)
+		}
+		content, err := migrationFiles.ReadFile(path.Join("migrations", name))
+		if err != nil {
+			return nil, err
+		}
+		var statements []string
+		for _, statement := range strings.Split(string(content), ";") {
+			if statement = strings.TrimSpace(statement); statement != "" {
+				statements = append(statements, statement)
+			}
+		}
+		migrations = append(migrations, Migration{
+			Version:    version,
+			Name:       strings.TrimSuffix(name, ".sql"),
+			Statements: statements,
+		})
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	for i, migration := range migrations {
+		if migration.Version != i+1 {
+			return nil, fmt.Errorf("migration %s is out of sequence, expected version %d", migration.Name, i+1)
+		}
+	}
+	if len(migrations) != SCHEMA_VERSION {
+		return nil, fmt.Errorf("found %d migrations, expected schema version %d", len(migrations), SCHEMA_VERSION)
+	}
+	return migrations, nil
+}
+
+func (m *MySQLRepository) Migrate(ctx context.Context) error {
+	migrations, err := Migrations()
+	if err != nil {
+		return err
+	}
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	var acquired sql.NullInt64
+	if err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, ?)", MIGRATION_LOCK, int(MIGRATION_LOCK_TIMEOUT.Seconds())).Scan(&acquired); err != nil {
+		return err
+	}
+	if acquired.Int64 != 1 {
+		return fmt.Errorf("could not acquire the %s lock within %s", MIGRATION_LOCK, MIGRATION_LOCK_TIMEOUT)
+	}
+	defer func() {
+		if _, err := conn.ExecContext(context.WithoutCancel(ctx), "SELECT RELEASE_LOCK(?)", MIGRATION_LOCK); err != nil {
+			slog.Error("could not release the migration lock", "error", err)
+		}
+	}()
+	_, err = conn.ExecContext(ctx, "CREATE TABLE IF NOT EXISTS schema_migrations (version INT PRIMARY KEY, applied_at TIMESTAMP NOT NULL DEFAULT NOW())")
+	if err != nil {
+		return err
+	}
+	var current sql.NullInt64
+	if err := conn.QueryRowContext(ctx, "SELECT MAX(version) FROM schema_migrations").Scan(&current); err != nil {
+		return err
+	}
+	for _, migration := range migrations {
+		if migration.Version <= int(current.Int64) {
+			continue
+		}
+		slog.Info("applying migration", "version", migration.Version, "name", migration.Name)
+		for _, statement := range migration.Statements {
+			if _, err = conn.ExecContext(ctx, statement); err != nil {
+				return fmt.Errorf("migration %s: %w", migration.Name, err)
+			}
+		}
+		if _, err = conn.ExecContext(ctx, "INSERT INTO schema_migrations (version) VALUES (?)", migration.Version); err != nil {
+			return fmt.Errorf("migration %s: %w", migration.Name, err)
+		}
+	}
+	return nil
+}
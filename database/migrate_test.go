@@ -0,0 +1,18 @@
+package database
+
+import "testing"
+
+func TestMigrationsMatchSchemaVersion(t *testing.T) {
+	migrations, err := Migrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if last := migrations[len(migrations)-1].Version; last != SCHEMA_VERSION {
+		t.Fatalf("latest migration is %d, SCHEMA_VERSION is %d", last, SCHEMA_VERSION)
+	}
+	for _, migration := range migrations {
+		if len(migration.Statements) == 0 {
+			t.Errorf("migration %s has no statements", migration.Name)
+		}
+	}
+}
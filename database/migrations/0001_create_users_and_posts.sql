@@ -1,15 +1,11 @@
-DROP TABLE IF EXISTS users;
-
-CREATE TABLE users (
+CREATE TABLE IF NOT EXISTS users (
     id VARCHAR(50) PRIMARY KEY,
     email VARCHAR(50) NOT NULL,
     password VARCHAR(255) NOT NULL,
     created_at TIMESTAMP NOT NULL DEFAULT NOW()
 );
 
-DROP TABLE IF EXISTS posts;
-
-CREATE TABLE posts (
+CREATE TABLE IF NOT EXISTS posts (
     id VARCHAR(50) PRIMARY KEY,
     user_id VARCHAR(50) NOT NULL,
     title VARCHAR(255) NOT NULL,
@@ -17,4 +13,4 @@ CREATE TABLE posts (
     created_at TIMESTAMP NOT NULL DEFAULT NOW(),
     INDEX (user_id),
     FOREIGN KEY (user_id) REFERENCES users(id)
-);
\ No newline at end of file
+);
@@ -0,0 +1,13 @@
+CREATE TABLE refresh_tokens (
+    id VARCHAR(50) PRIMARY KEY,
+    user_id VARCHAR(50) NOT NULL,
+    family_id VARCHAR(50) NOT NULL,
+    token_hash CHAR(64) NOT NULL UNIQUE,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    revoked_at TIMESTAMP NULL,
+    replaced_by VARCHAR(50) NULL,
+    INDEX (user_id),
+    INDEX (family_id),
+    FOREIGN KEY (user_id) REFERENCES users(id)
+);
@@ -0,0 +1,19 @@
+CREATE TABLE role_permissions (
+    role VARCHAR(50) NOT NULL,
+    permission VARCHAR(50) NOT NULL,
+    PRIMARY KEY (role, permission)
+);
+
+INSERT INTO role_permissions (role, permission) VALUES
+    ('moderator', 'posts:edit_any'),
+    ('moderator', 'posts:delete_any'),
+    ('admin', 'posts:edit_any'),
+    ('admin', 'posts:delete_any'),
+    ('admin', 'users:manage');
+
+CREATE TABLE user_roles (
+    user_id VARCHAR(50) NOT NULL,
+    role VARCHAR(50) NOT NULL,
+    PRIMARY KEY (user_id, role),
+    FOREIGN KEY (user_id) REFERENCES users(id)
+);
@@ -0,0 +1,10 @@
+CREATE TABLE password_reset_tokens (
+    id VARCHAR(50) PRIMARY KEY,
+    user_id VARCHAR(50) NOT NULL,
+    token_hash CHAR(64) NOT NULL UNIQUE,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    INDEX (user_id),
+    FOREIGN KEY (user_id) REFERENCES users(id)
+);
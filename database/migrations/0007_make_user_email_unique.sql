@@ -0,0 +1,3 @@
+UPDATE users SET email = LOWER(TRIM(email));
+
+ALTER TABLE users MODIFY email VARCHAR(255) NOT NULL, ADD UNIQUE (email);
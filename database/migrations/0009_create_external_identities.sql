@@ -0,0 +1,11 @@
+CREATE TABLE external_identities (
+    id VARCHAR(50) PRIMARY KEY,
+    user_id VARCHAR(50) NOT NULL,
+    provider VARCHAR(255) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    email VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (provider, subject),
+    INDEX (user_id),
+    FOREIGN KEY (user_id) REFERENCES users(id)
+);
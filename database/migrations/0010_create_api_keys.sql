@@ -0,0 +1,13 @@
+CREATE TABLE api_keys (
+    id VARCHAR(50) PRIMARY KEY,
+    user_id VARCHAR(50) NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    prefix VARCHAR(20) NOT NULL,
+    key_hash CHAR(64) NOT NULL UNIQUE,
+    scopes VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    last_used_at TIMESTAMP NULL,
+    revoked_at TIMESTAMP NULL,
+    INDEX (user_id),
+    FOREIGN KEY (user_id) REFERENCES users(id)
+);
@@ -0,0 +1,5 @@
+CREATE TABLE login_attempts (
+    attempt_key VARCHAR(300) PRIMARY KEY,
+    failures INT NOT NULL DEFAULT 0,
+    last_failure_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,13 @@
+CREATE TABLE idempotency_keys (
+    user_id VARCHAR(50) NOT NULL,
+    idempotency_key VARCHAR(255) NOT NULL,
+    fingerprint CHAR(64) NOT NULL,
+    status_code INT NOT NULL DEFAULT 0,
+    headers TEXT NULL,
+    body MEDIUMBLOB NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    completed_at TIMESTAMP NULL,
+    expires_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (user_id, idempotency_key),
+    FOREIGN KEY (user_id) REFERENCES users(id)
+);
@@ -3,138 +3,748 @@ package database
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"math"
+	"strings"
+	"time"
 
-	_ "github.com/go-sql-driver/mysql"
+	"github.com/go-sql-driver/mysql"
+	"github.com/segmentio/ksuid"
 
 	"github.com/th3khan/rest-web-sockets-with-go/models"
+	"github.com/th3khan/rest-web-sockets-with-go/repositories"
+	"github.com/th3khan/rest-web-sockets-with-go/tracing"
 )
 
+const (
+	SCHEMA_VERSION = 15
+
+	ER_DUP_ENTRY = 1062
+)
+
+type MySQLConfig struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+	PingRetries     int
+	PingBackoff     time.Duration
+	AutoMigrate     bool
+}
+
 type MySQLRepository struct {
 	db *sql.DB
 }
 
-func NewMySQLRepository(url string) (*MySQLRepository, error) {
+func NewMySQLRepository(ctx context.Context, url string, config *MySQLConfig) (*MySQLRepository, error) {
 	db, err := sql.Open("mysql", url)
 	if err != nil {
 		return nil, err
 	}
-	return &MySQLRepository{
+	if config == nil {
+		config = &MySQLConfig{}
+	}
+	db.SetMaxOpenConns(config.MaxOpenConns)
+	db.SetMaxIdleConns(config.MaxIdleConns)
+	db.SetConnMaxLifetime(config.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(config.ConnMaxIdleTime)
+
+	repo := &MySQLRepository{
 		db: db,
-	}, nil
+	}
+	if err = repo.pingWithRetry(ctx, config.PingRetries, config.PingBackoff); err != nil {
+		db.Close()
+		return nil, err
+	}
+	if config.AutoMigrate {
+		if err = repo.Migrate(ctx); err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+	return repo, nil
 }
 
-func (m *MySQLRepository) InsertUser(ctx context.Context, user *models.User) error {
-	_, err := m.db.ExecContext(ctx, "INSERT INTO users (id, email, password) VALUES (?, ?, ?)", user.ID, user.Email, user.Password)
+func (m *MySQLRepository) pingWithRetry(ctx context.Context, retries int, backoff time.Duration) error {
+	if backoff <= 0 {
+		backoff = time.Second
+	}
+	var err error
+	for attempt := 0; attempt <= retries; attempt++ {
+		if err = m.Ping(ctx); err == nil {
+			return nil
+		}
+		if attempt == retries {
+			break
+		}
+		slog.Warn("database not reachable", "attempt", attempt+1, "attempts", retries+1, "error", err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+	return fmt.Errorf("database ping failed: %w", err)
+}
+
+func translateError(err error) error {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == ER_DUP_ENTRY {
+		return repositories.ErrDuplicate
+	}
 	return err
 }
 
+func (m *MySQLRepository) exec(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	tracing.SetStatement(ctx, query)
+	return m.db.ExecContext(ctx, query, args...)
+}
+
+func (m *MySQLRepository) query(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	tracing.SetStatement(ctx, query)
+	return m.db.QueryContext(ctx, query, args...)
+}
+
+func (m *MySQLRepository) queryRow(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	tracing.SetStatement(ctx, query)
+	return m.db.QueryRowContext(ctx, query, args...)
+}
+
+func (m *MySQLRepository) Ping(ctx context.Context) error {
+	return m.db.PingContext(ctx)
+}
+
+func (m *MySQLRepository) InsertUser(ctx context.Context, user *models.User) error {
+	_, err := m.exec(ctx, "INSERT INTO users (id, email, password) VALUES (?, ?, ?)", user.ID, user.Email, user.Password)
+	return translateError(err)
+}
+
 func (m *MySQLRepository) GetUserById(ctx context.Context, id string) (*models.User, error) {
-	rows, err := m.db.QueryContext(ctx, "SELECT id, email FROM users WHERE id = ?", id)
+	return m.getUser(ctx, "SELECT id, email, password, email_verified_at, token_version FROM users WHERE id = ?", id)
+}
 
-	defer func() {
-		err = rows.Close()
-		if err != nil {
-			log.Fatal(err)
-		}
-	}()
+func (m *MySQLRepository) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	return m.getUser(ctx, "SELECT id, email, password, email_verified_at, token_version FROM users WHERE email = ?", email)
+}
 
+func (m *MySQLRepository) getUser(ctx context.Context, query string, args ...interface{}) (*models.User, error) {
 	var user models.User
+	var verifiedAt sql.NullTime
+	err := m.queryRow(ctx, query, args...).Scan(&user.ID, &user.Email, &user.Password, &verifiedAt, &user.TokenVersion)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	user.EmailVerified = verifiedAt.Valid
+	return &user, nil
+}
+
+func (m *MySQLRepository) MarkEmailVerified(ctx context.Context, userID string) error {
+	_, err := m.exec(ctx, "UPDATE users SET email_verified_at = NOW() WHERE id = ? AND email_verified_at IS NULL", userID)
+	return err
+}
+
+func (m *MySQLRepository) UpdateUserPassword(ctx context.Context, userID string, password string) error {
+	_, err := m.exec(ctx, "UPDATE users SET password = ? WHERE id = ?", password, userID)
+	return err
+}
+
+func (m *MySQLRepository) InsertPost(ctx context.Context, post *models.Post) error {
+	_, err := m.exec(ctx, "INSERT INTO posts (id, title, content, user_id, created_at) VALUES (?, ?, ?, ?, ?)", post.ID, post.Title, post.Content, post.UserID, post.CreatedAt)
+	return err
+}
+
+func (m *MySQLRepository) GetPostById(ctx context.Context, id string) (*models.Post, error) {
+	var post models.Post
+	err := m.queryRow(ctx, "SELECT id, title, content, user_id, created_at FROM posts WHERE id = ?", id).
+		Scan(&post.ID, &post.Title, &post.Content, &post.UserID, &post.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &post, nil
+}
+
+func (m *MySQLRepository) UpdatePost(ctx context.Context, post *models.Post) error {
+	result, err := m.exec(ctx, "UPDATE posts SET title = ?, content = ? WHERE id = ? AND user_id = ?", post.Title, post.Content, post.ID, post.UserID)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil || affected > 0 {
+		return err
+	}
+	var exists int
+	err = m.queryRow(ctx, "SELECT 1 FROM posts WHERE id = ? AND user_id = ?", post.ID, post.UserID).Scan(&exists)
+	if err == sql.ErrNoRows {
+		return repositories.ErrNotFound
+	}
+	return err
+}
+
+func (m *MySQLRepository) DeletePost(ctx context.Context, id string, userId string) error {
+	result, err := m.exec(ctx, "DELETE FROM posts WHERE id = ? AND user_id = ?", id, userId)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return repositories.ErrNotFound
+	}
+	return nil
+}
+
+func (m *MySQLRepository) ListPosts(ctx context.Context, page uint64) ([]*models.Post, error) {
+	rows, err := m.query(ctx, "SELECT id, title, content, user_id, created_at FROM posts ORDER BY created_at DESC LIMIT ? OFFSET ?", 2, page*2)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var posts []*models.Post
 	for rows.Next() {
-		if err = rows.Scan(&user.ID, &user.Email); err == nil {
-			return &user, nil
+		var post = models.Post{}
+		if err = rows.Scan(&post.ID, &post.Title, &post.Content, &post.UserID, &post.CreatedAt); err != nil {
+			return nil, err
 		}
+		posts = append(posts, &post)
 	}
+	return posts, rows.Err()
+}
 
-	if err = rows.Err(); err != nil {
+func (m *MySQLRepository) InsertRefreshToken(ctx context.Context, token *models.RefreshToken) error {
+	_, err := m.exec(ctx, "INSERT INTO refresh_tokens (id, user_id, family_id, token_hash, expires_at) VALUES (?, ?, ?, ?, ?)", token.ID, token.UserID, token.FamilyID, token.TokenHash, token.ExpiresAt)
+	return err
+}
+
+func (m *MySQLRepository) GetRefreshTokenByHash(ctx context.Context, hash string) (*models.RefreshToken, error) {
+	var token models.RefreshToken
+	var revokedAt sql.NullTime
+	var replacedBy sql.NullString
+	err := m.queryRow(ctx, "SELECT id, user_id, family_id, token_hash, expires_at, created_at, revoked_at, replaced_by FROM refresh_tokens WHERE token_hash = ?", hash).
+		Scan(&token.ID, &token.UserID, &token.FamilyID, &token.TokenHash, &token.ExpiresAt, &token.CreatedAt, &revokedAt, &replacedBy)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
 		return nil, err
 	}
+	if revokedAt.Valid {
+		token.RevokedAt = &revokedAt.Time
+	}
+	token.ReplacedBy = replacedBy.String
+	return &token, nil
+}
 
-	return &user, nil
+func (m *MySQLRepository) RevokeRefreshToken(ctx context.Context, id string, replacedBy string) (bool, error) {
+	result, err := m.exec(ctx, "UPDATE refresh_tokens SET revoked_at = NOW(), replaced_by = NULLIF(?, '') WHERE id = ? AND revoked_at IS NULL", replacedBy, id)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
 }
-func (m *MySQLRepository) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
-	rows, err := m.db.QueryContext(ctx, "SELECT id, email, password FROM users WHERE email = ?", email)
 
-	defer func() {
-		err = rows.Close()
-		if err != nil {
-			log.Fatal(err)
+func (m *MySQLRepository) RevokeRefreshTokenFamily(ctx context.Context, familyID string) error {
+	if _, err := m.exec(ctx, "UPDATE sessions SET revoked_at = NOW() WHERE id = ? AND revoked_at IS NULL", familyID); err != nil {
+		return err
+	}
+	_, err := m.exec(ctx, "UPDATE refresh_tokens SET revoked_at = NOW() WHERE family_id = ? AND revoked_at IS NULL", familyID)
+	return err
+}
+
+func (m *MySQLRepository) InsertRevokedToken(ctx context.Context, token *models.RevokedToken) error {
+	_, err := m.exec(ctx, "INSERT IGNORE INTO revoked_tokens (id, user_id, expires_at) VALUES (?, ?, ?)", token.ID, token.UserID, token.ExpiresAt)
+	return err
+}
+
+func (m *MySQLRepository) IsTokenRevoked(ctx context.Context, id string) (bool, error) {
+	var count int
+	err := m.queryRow(ctx, "SELECT COUNT(*) FROM revoked_tokens WHERE id = ?", id).Scan(&count)
+	return count > 0, err
+}
+
+func (m *MySQLRepository) RevokeUserTokens(ctx context.Context, userID string) error {
+	if _, err := m.exec(ctx, "UPDATE users SET token_version = token_version + 1 WHERE id = ?", userID); err != nil {
+		return err
+	}
+	if _, err := m.exec(ctx, "UPDATE sessions SET revoked_at = NOW() WHERE user_id = ? AND revoked_at IS NULL", userID); err != nil {
+		return err
+	}
+	_, err := m.exec(ctx, "UPDATE refresh_tokens SET revoked_at = NOW() WHERE user_id = ? AND revoked_at IS NULL", userID)
+	return err
+}
+
+func (m *MySQLRepository) GetUserTokenVersion(ctx context.Context, userID string) (int, error) {
+	var version int
+	err := m.queryRow(ctx, "SELECT token_version FROM users WHERE id = ?", userID).Scan(&version)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return version, err
+}
+
+func (m *MySQLRepository) InsertPasswordResetToken(ctx context.Context, token *models.PasswordResetToken) error {
+	_, err := m.exec(ctx, "INSERT INTO password_reset_tokens (id, user_id, token_hash, expires_at) VALUES (?, ?, ?, ?)", token.ID, token.UserID, token.TokenHash, token.ExpiresAt)
+	return err
+}
+
+func (m *MySQLRepository) GetPasswordResetTokenByHash(ctx context.Context, hash string) (*models.PasswordResetToken, error) {
+	var token models.PasswordResetToken
+	var usedAt sql.NullTime
+	err := m.queryRow(ctx, "SELECT id, user_id, token_hash, expires_at, used_at FROM password_reset_tokens WHERE token_hash = ?", hash).
+		Scan(&token.ID, &token.UserID, &token.TokenHash, &token.ExpiresAt, &usedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if usedAt.Valid {
+		token.UsedAt = &usedAt.Time
+	}
+	return &token, nil
+}
+
+func (m *MySQLRepository) MarkPasswordResetTokenUsed(ctx context.Context, id string) (bool, error) {
+	result, err := m.exec(ctx, "UPDATE password_reset_tokens SET used_at = NOW() WHERE id = ? AND used_at IS NULL", id)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+func (m *MySQLRepository) InvalidatePasswordResetTokens(ctx context.Context, userID string) error {
+	_, err := m.exec(ctx, "UPDATE password_reset_tokens SET used_at = NOW() WHERE user_id = ? AND used_at IS NULL", userID)
+	return err
+}
+
+func (m *MySQLRepository) InsertEmailVerificationToken(ctx context.Context, token *models.EmailVerificationToken) error {
+	_, err := m.exec(ctx, "INSERT INTO email_verification_tokens (id, user_id, email, token_hash, expires_at) VALUES (?, ?, ?, ?, ?)", token.ID, token.UserID, token.Email, token.TokenHash, token.ExpiresAt)
+	return err
+}
+
+func (m *MySQLRepository) GetEmailVerificationTokenByHash(ctx context.Context, hash string) (*models.EmailVerificationToken, error) {
+	var token models.EmailVerificationToken
+	var usedAt sql.NullTime
+	err := m.queryRow(ctx, "SELECT id, user_id, email, token_hash, expires_at, used_at FROM email_verification_tokens WHERE token_hash = ?", hash).
+		Scan(&token.ID, &token.UserID, &token.Email, &token.TokenHash, &token.ExpiresAt, &usedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if usedAt.Valid {
+		token.UsedAt = &usedAt.Time
+	}
+	return &token, nil
+}
+
+func (m *MySQLRepository) MarkEmailVerificationTokenUsed(ctx context.Context, id string) (bool, error) {
+	result, err := m.exec(ctx, "UPDATE email_verification_tokens SET used_at = NOW() WHERE id = ? AND used_at IS NULL", id)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+func (m *MySQLRepository) GetTOTP(ctx context.Context, userID string) (*models.TOTP, error) {
+	var totp models.TOTP
+	var confirmedAt sql.NullTime
+	err := m.queryRow(ctx, "SELECT user_id, secret, confirmed_at, last_used_step FROM user_totp WHERE user_id = ?", userID).
+		Scan(&totp.UserID, &totp.Secret, &confirmedAt, &totp.LastUsedStep)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if confirmedAt.Valid {
+		totp.ConfirmedAt = &confirmedAt.Time
+	}
+	return &totp, nil
+}
+
+func (m *MySQLRepository) UpsertTOTP(ctx context.Context, totp *models.TOTP) error {
+	_, err := m.exec(ctx, "INSERT INTO user_totp (user_id, secret) VALUES (?, ?) ON DUPLICATE KEY UPDATE secret = IF(confirmed_at IS NULL, VALUES(secret), secret)", totp.UserID, totp.Secret)
+	return err
+}
+
+func (m *MySQLRepository) ConfirmTOTP(ctx context.Context, userID string) error {
+	_, err := m.exec(ctx, "UPDATE user_totp SET confirmed_at = NOW() WHERE user_id = ? AND confirmed_at IS NULL", userID)
+	return err
+}
+
+func (m *MySQLRepository) DeleteTOTP(ctx context.Context, userID string) error {
+	if _, err := m.exec(ctx, "DELETE FROM recovery_codes WHERE user_id = ?", userID); err != nil {
+		return err
+	}
+	_, err := m.exec(ctx, "DELETE FROM user_totp WHERE user_id = ?", userID)
+	return err
+}
+
+func (m *MySQLRepository) UseTOTPStep(ctx context.Context, userID string, step int64) (bool, error) {
+	result, err := m.exec(ctx, "UPDATE user_totp SET last_used_step = ? WHERE user_id = ? AND last_used_step < ?", step, userID, step)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+func (m *MySQLRepository) ReplaceRecoveryCodes(ctx context.Context, userID string, hashes []string) error {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err = tx.ExecContext(ctx, "DELETE FROM recovery_codes WHERE user_id = ?", userID); err != nil {
+		return err
+	}
+	for _, hash := range hashes {
+		if _, err = tx.ExecContext(ctx, "INSERT INTO recovery_codes (id, user_id, code_hash) VALUES (?, ?, ?)", ksuid.New().String(), userID, hash); err != nil {
+			return err
 		}
-	}()
+	}
+	return tx.Commit()
+}
 
-	var user models.User
+func (m *MySQLRepository) UseRecoveryCode(ctx context.Context, userID string, hash string) (bool, error) {
+	result, err := m.exec(ctx, "UPDATE recovery_codes SET used_at = NOW() WHERE user_id = ? AND code_hash = ? AND used_at IS NULL LIMIT 1", userID, hash)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+func (m *MySQLRepository) InsertExternalIdentity(ctx context.Context, identity *models.ExternalIdentity) error {
+	_, err := m.exec(ctx, "INSERT INTO external_identities (id, user_id, provider, subject, email) VALUES (?, ?, ?, ?, ?)", identity.ID, identity.UserID, identity.Provider, identity.Subject, identity.Email)
+	return translateError(err)
+}
+
+func (m *MySQLRepository) GetExternalIdentity(ctx context.Context, provider string, subject string) (*models.ExternalIdentity, error) {
+	var identity models.ExternalIdentity
+	err := m.queryRow(ctx, "SELECT id, user_id, provider, subject, email, created_at FROM external_identities WHERE provider = ? AND subject = ?", provider, subject).
+		Scan(&identity.ID, &identity.UserID, &identity.Provider, &identity.Subject, &identity.Email, &identity.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &identity, nil
+}
+
+func (m *MySQLRepository) InsertAPIKey(ctx context.Context, key *models.APIKey) error {
+	_, err := m.exec(ctx, "INSERT INTO api_keys (id, user_id, name, prefix, key_hash, scopes) VALUES (?, ?, ?, ?, ?, ?)", key.ID, key.UserID, key.Name, key.Prefix, key.KeyHash, strings.Join(key.Scopes, ","))
+	return translateError(err)
+}
+
+func (m *MySQLRepository) scanAPIKey(scanner interface{ Scan(...interface{}) error }) (*models.APIKey, error) {
+	var key models.APIKey
+	var scopes string
+	var lastUsedAt, revokedAt sql.NullTime
+	err := scanner.Scan(&key.ID, &key.UserID, &key.Name, &key.Prefix, &key.KeyHash, &scopes, &key.CreatedAt, &lastUsedAt, &revokedAt)
+	if err != nil {
+		return nil, err
+	}
+	if scopes != "" {
+		key.Scopes = strings.Split(scopes, ",")
+	}
+	if lastUsedAt.Valid {
+		key.LastUsedAt = &lastUsedAt.Time
+	}
+	if revokedAt.Valid {
+		key.RevokedAt = &revokedAt.Time
+	}
+	return &key, nil
+}
+
+func (m *MySQLRepository) GetAPIKeyByHash(ctx context.Context, hash string) (*models.APIKey, error) {
+	key, err := m.scanAPIKey(m.queryRow(ctx, "SELECT id, user_id, name, prefix, key_hash, scopes, created_at, last_used_at, revoked_at FROM api_keys WHERE key_hash = ?", hash))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return key, err
+}
+
+func (m *MySQLRepository) ListAPIKeys(ctx context.Context, userID string) ([]*models.APIKey, error) {
+	rows, err := m.query(ctx, "SELECT id, user_id, name, prefix, key_hash, scopes, created_at, last_used_at, revoked_at FROM api_keys WHERE user_id = ? ORDER BY created_at DESC", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	keys := []*models.APIKey{}
 	for rows.Next() {
-		if err = rows.Scan(&user.ID, &user.Email, &user.Password); err == nil {
-			return &user, nil
+		key, err := m.scanAPIKey(rows)
+		if err != nil {
+			return nil, err
 		}
+		keys = append(keys, key)
 	}
+	return keys, rows.Err()
+}
 
-	if err = rows.Err(); err != nil {
+func (m *MySQLRepository) RevokeAPIKey(ctx context.Context, id string, userID string) (bool, error) {
+	result, err := m.exec(ctx, "UPDATE api_keys SET revoked_at = NOW() WHERE id = ? AND user_id = ? AND revoked_at IS NULL", id, userID)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+func (m *MySQLRepository) TouchAPIKey(ctx context.Context, id string) error {
+	_, err := m.exec(ctx, "UPDATE api_keys SET last_used_at = NOW() WHERE id = ? AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL 1 MINUTE)", id)
+	return err
+}
+
+func (m *MySQLRepository) GetLoginAttempts(ctx context.Context, key string) (*models.LoginAttempts, error) {
+	var attempts models.LoginAttempts
+	err := m.queryRow(ctx, "SELECT attempt_key, failures, last_failure_at FROM login_attempts WHERE attempt_key = ?", key).
+		Scan(&attempts.Key, &attempts.Failures, &attempts.LastFailureAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
 		return nil, err
 	}
+	return &attempts, nil
+}
 
-	return &user, nil
+func (m *MySQLRepository) RecordLoginFailure(ctx context.Context, key string, window time.Duration) (*models.LoginAttempts, error) {
+	_, err := m.exec(ctx, "INSERT INTO login_attempts (attempt_key, failures, last_failure_at) VALUES (?, 1, NOW()) ON DUPLICATE KEY UPDATE failures = IF(last_failure_at < NOW() - INTERVAL ? SECOND, 1, failures + 1), last_failure_at = NOW()", key, int64(window.Seconds()))
+	if err != nil {
+		return nil, err
+	}
+	return m.GetLoginAttempts(ctx, key)
 }
 
-func (m *MySQLRepository) InsertPost(ctx context.Context, post *models.Post) error {
-	_, err := m.db.ExecContext(ctx, "INSERT INTO posts (id, title, content, user_id) VALUES (?, ?, ?, ?)", post.ID, post.Title, post.Content, post.UserID)
+func (m *MySQLRepository) ResetLoginAttempts(ctx context.Context, key string) error {
+	_, err := m.exec(ctx, "DELETE FROM login_attempts WHERE attempt_key = ?", key)
 	return err
 }
 
-func (m *MySQLRepository) GetPostById(ctx context.Context, id string) (*models.Post, error) {
-	rows, err := m.db.QueryContext(ctx, "SELECT id, title, content, user_id, created_at FROM posts WHERE id = ?", id)
-	defer func() {
-		err = rows.Close()
-		if err != nil {
-			log.Fatal(err)
-		}
-	}()
-	var post models.Post
+func (m *MySQLRepository) ReleaseLoginAttempt(ctx context.Context, key string) error {
+	_, err := m.exec(ctx, "UPDATE login_attempts SET failures = failures - 1 WHERE attempt_key = ? AND failures > 0", key)
+	return err
+}
+
+func (m *MySQLRepository) InsertSession(ctx context.Context, session *models.Session) error {
+	_, err := m.exec(ctx, "INSERT INTO sessions (id, user_id, user_agent, ip, expires_at) VALUES (?, ?, ?, ?, ?)", session.ID, session.UserID, session.UserAgent, session.IP, session.ExpiresAt)
+	return err
+}
+
+func (m *MySQLRepository) RefreshSession(ctx context.Context, id string, ip string, expiresAt time.Time) error {
+	_, err := m.exec(ctx, "UPDATE sessions SET last_seen_at = NOW(), ip = ?, expires_at = ? WHERE id = ?", ip, expiresAt, id)
+	return err
+}
+
+func (m *MySQLRepository) TouchSession(ctx context.Context, id string) error {
+	_, err := m.exec(ctx, "UPDATE sessions SET last_seen_at = NOW() WHERE id = ? AND last_seen_at < NOW() - INTERVAL 1 MINUTE", id)
+	return err
+}
+
+func (m *MySQLRepository) ListSessions(ctx context.Context, userID string) ([]*models.Session, error) {
+	rows, err := m.query(ctx, "SELECT id, user_id, user_agent, ip, created_at, last_seen_at, expires_at FROM sessions WHERE user_id = ? AND revoked_at IS NULL AND expires_at > NOW() ORDER BY last_seen_at DESC", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	sessions := []*models.Session{}
 	for rows.Next() {
-		if err = rows.Scan(&post.ID, &post.Title, &post.Content, &post.UserID, &post.CreatedAt); err == nil {
-			return &post, nil
+		var session models.Session
+		if err = rows.Scan(&session.ID, &session.UserID, &session.UserAgent, &session.IP, &session.CreatedAt, &session.LastSeenAt, &session.ExpiresAt); err != nil {
+			return nil, err
 		}
+		sessions = append(sessions, &session)
+	}
+	return sessions, rows.Err()
+}
+
+func (m *MySQLRepository) RevokeSession(ctx context.Context, id string, userID string) (bool, error) {
+	result, err := m.exec(ctx, "UPDATE sessions SET revoked_at = NOW() WHERE id = ? AND user_id = ? AND revoked_at IS NULL", id, userID)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil || affected == 0 {
+		return false, err
+	}
+	_, err = m.exec(ctx, "UPDATE refresh_tokens SET revoked_at = NOW() WHERE family_id = ? AND revoked_at IS NULL", id)
+	return err == nil, err
+}
+
+func (m *MySQLRepository) IsSessionRevoked(ctx context.Context, id string) (bool, error) {
+	var count int
+	err := m.queryRow(ctx, "SELECT COUNT(*) FROM sessions WHERE id = ? AND revoked_at IS NOT NULL", id).Scan(&count)
+	return count > 0, err
+}
+
+func (m *MySQLRepository) TakeRateLimitToken(ctx context.Context, key string, capacity float64, rate float64) (float64, bool, error) {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, false, err
+	}
+	defer tx.Rollback()
+	if _, err = tx.ExecContext(ctx, "INSERT IGNORE INTO rate_limit_buckets (bucket_key, tokens, updated_at) VALUES (?, ?, NOW(6))", key, capacity); err != nil {
+		return 0, false, err
+	}
+	var tokens float64
+	var elapsed int64
+	err = tx.QueryRowContext(ctx, "SELECT tokens, TIMESTAMPDIFF(MICROSECOND, updated_at, NOW(6)) FROM rate_limit_buckets WHERE bucket_key = ? FOR UPDATE", key).
+		Scan(&tokens, &elapsed)
+	if err != nil {
+		return 0, false, err
+	}
+	tokens = math.Min(capacity, tokens+float64(elapsed)/1e6*rate)
+	allowed := tokens >= 1
+	if allowed {
+		tokens--
+	}
+	if _, err = tx.ExecContext(ctx, "UPDATE rate_limit_buckets SET tokens = ?, updated_at = NOW(6) WHERE bucket_key = ?", tokens, key); err != nil {
+		return 0, false, err
+	}
+	return tokens, allowed, tx.Commit()
+}
+
+func (m *MySQLRepository) ReserveIdempotencyKey(ctx context.Context, record *models.IdempotencyRecord) (bool, error) {
+	if _, err := m.exec(ctx, "DELETE FROM idempotency_keys WHERE user_id = ? AND idempotency_key = ? AND expires_at < NOW()", record.UserID, record.Key); err != nil {
+		return false, err
+	}
+	result, err := m.exec(ctx, "INSERT IGNORE INTO idempotency_keys (user_id, idempotency_key, fingerprint, expires_at) VALUES (?, ?, ?, ?)", record.UserID, record.Key, record.Fingerprint, record.ExpiresAt)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+func (m *MySQLRepository) GetIdempotencyRecord(ctx context.Context, userID string, key string) (*models.IdempotencyRecord, error) {
+	var record models.IdempotencyRecord
+	var header sql.NullString
+	var completedAt sql.NullTime
+	err := m.queryRow(ctx, "SELECT user_id, idempotency_key, fingerprint, status_code, headers, body, completed_at, expires_at FROM idempotency_keys WHERE user_id = ? AND idempotency_key = ? AND expires_at > NOW()", userID, key).
+		Scan(&record.UserID, &record.Key, &record.Fingerprint, &record.StatusCode, &header, &record.Body, &completedAt, &record.ExpiresAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
 	}
-	if err = rows.Err(); err != nil {
+	if err != nil {
 		return nil, err
 	}
-	return &post, nil
+	if header.Valid {
+		if err = json.Unmarshal([]byte(header.String), &record.Header); err != nil {
+			return nil, err
+		}
+	}
+	if completedAt.Valid {
+		record.CompletedAt = &completedAt.Time
+	}
+	return &record, nil
 }
 
-func (m *MySQLRepository) UpdatePost(ctx context.Context, post *models.Post) error {
-	_, err := m.db.ExecContext(ctx, "UPDATE posts SET title = ?, content = ? WHERE id = ? AND user_id = ?", post.Title, post.Content, post.ID, post.UserID)
+func (m *MySQLRepository) CompleteIdempotencyRecord(ctx context.Context, record *models.IdempotencyRecord) error {
+	header, err := json.Marshal(record.Header)
+	if err != nil {
+		return err
+	}
+	_, err = m.exec(ctx, "UPDATE idempotency_keys SET status_code = ?, headers = ?, body = ?, completed_at = ?, expires_at = ? WHERE user_id = ? AND idempotency_key = ?", record.StatusCode, string(header), record.Body, record.CompletedAt, record.ExpiresAt, record.UserID, record.Key)
 	return err
 }
 
-func (m *MySQLRepository) DeletePost(ctx context.Context, id string, userId string) error {
-	_, err := m.db.ExecContext(ctx, "DELETE FROM posts WHERE id = ? AND user_id = ?", id, userId)
+func (m *MySQLRepository) DeleteIdempotencyRecord(ctx context.Context, userID string, key string) error {
+	_, err := m.exec(ctx, "DELETE FROM idempotency_keys WHERE user_id = ? AND idempotency_key = ?", userID, key)
 	return err
 }
 
-func (m *MySQLRepository) ListPosts(ctx context.Context, page uint64) ([]*models.Post, error) {
-	rows, err := m.db.QueryContext(ctx, "SELECT id, title, content, user_id, created_at FROM posts ORDER BY created_at DESC LIMIT ? OFFSET ?", 2, page*2)
-	defer func() {
-		err = rows.Close()
-		if err != nil {
-			log.Fatal(err)
-		}
-	}()
+func (m *MySQLRepository) ListUsers(ctx context.Context, page uint64) ([]*models.User, error) {
+	rows, err := m.query(ctx, "SELECT id, email FROM users ORDER BY created_at DESC LIMIT ? OFFSET ?", 20, page*20)
 	if err != nil {
 		return nil, err
 	}
-	var posts []*models.Post
+	defer rows.Close()
+	var users []*models.User
 	for rows.Next() {
-		var post = models.Post{}
-		if err = rows.Scan(&post.ID, &post.Title, &post.Content, &post.UserID, &post.CreatedAt); err == nil {
-			posts = append(posts, &post)
+		var user = models.User{}
+		if err = rows.Scan(&user.ID, &user.Email); err != nil {
+			return nil, err
 		}
+		users = append(users, &user)
+	}
+	return users, rows.Err()
+}
+
+func (m *MySQLRepository) GetUserRoles(ctx context.Context, userID string) ([]string, error) {
+	return m.queryStrings(ctx, "SELECT role FROM user_roles WHERE user_id = ? ORDER BY role", userID)
+}
+
+func (m *MySQLRepository) GetRolePermissions(ctx context.Context, roles []string) ([]string, error) {
+	if len(roles) == 0 {
+		return nil, nil
 	}
-	fmt.Println(posts)
-	if err = rows.Err(); err != nil {
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(roles)), ", ")
+	args := make([]interface{}, len(roles))
+	for i, role := range roles {
+		args[i] = role
+	}
+	return m.queryStrings(ctx, "SELECT DISTINCT permission FROM role_permissions WHERE role IN ("+placeholders+") ORDER BY permission", args...)
+}
+
+func (m *MySQLRepository) SetUserRoles(ctx context.Context, userID string, roles []string) error {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err = tx.ExecContext(ctx, "DELETE FROM user_roles WHERE user_id = ?", userID); err != nil {
+		return err
+	}
+	for _, role := range roles {
+		if _, err = tx.ExecContext(ctx, "INSERT INTO user_roles (user_id, role) VALUES (?, ?)", userID, role); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (m *MySQLRepository) queryStrings(ctx context.Context, query string, args ...interface{}) ([]string, error) {
+	rows, err := m.query(ctx, query, args...)
+	if err != nil {
 		return nil, err
 	}
-	return posts, nil
+	defer rows.Close()
+	var values []string
+	for rows.Next() {
+		var value string
+		if err = rows.Scan(&value); err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, rows.Err()
+}
+
+func (m *MySQLRepository) SchemaVersion(ctx context.Context) (int, error) {
+	var version sql.NullInt64
+	err := m.queryRow(ctx, "SELECT MAX(version) FROM schema_migrations").Scan(&version)
+	if err != nil {
+		return 0, err
+	}
+	return int(version.Int64), nil
 }
 
 func (m *MySQLRepository) Close() error {
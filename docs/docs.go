@@ -0,0 +1,135 @@
+package docs
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/th3khan/rest-web-sockets-with-go/events"
+	"github.com/th3khan/rest-web-sockets-with-go/problem"
+)
+
+//go:embed openapi.json
+var spec []byte
+
+//go:embed index.html
+var index []byte
+
+//go:embed ui
+var assets embed.FS
+
+const CONTENT_SECURITY_POLICY = "default-src 'self'; img-src 'self' data:; frame-ancestors 'none'"
+
+type document struct {
+	Paths map[string]map[string]json.RawMessage `json:"paths"`
+}
+
+func SpecHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(spec)
+	}
+}
+
+func UIHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Content-Security-Policy", CONTENT_SECURITY_POLICY)
+		w.WriteHeader(http.StatusOK)
+		w.Write(index)
+	}
+}
+
+func AssetHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := mux.Vars(r)["asset"]
+		content, err := assets.ReadFile(path.Join("ui", path.Base(name)))
+		if err != nil {
+			problem.Error(w, r, http.StatusNotFound, problem.CODE_NOT_FOUND, "asset not found")
+			return
+		}
+		w.Header().Set("Content-Type", mime.TypeByExtension(path.Ext(name)))
+		w.Header().Set("Cache-Control", "public, max-age=3600")
+		w.WriteHeader(http.StatusOK)
+		w.Write(content)
+	}
+}
+
+func AsyncAPIHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		protocol := "ws"
+		if r.TLS != nil {
+			protocol = "wss"
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(events.AsyncAPI(r.Host, protocol))
+	}
+}
+
+func Operations() (map[string]bool, error) {
+	var doc document
+	if err := json.Unmarshal(spec, &doc); err != nil {
+		return nil, err
+	}
+	operations := map[string]bool{}
+	for path, item := range doc.Paths {
+		for method := range item {
+			operations[strings.ToUpper(method)+" "+path] = true
+		}
+	}
+	return operations, nil
+}
+
+func RouterOperations(router *mux.Router) (map[string]bool, error) {
+	operations := map[string]bool{}
+	err := router.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		if route.GetHandler() == nil {
+			return nil
+		}
+		path, err := route.GetPathTemplate()
+		if err != nil {
+			return nil
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			methods = []string{http.MethodGet}
+		}
+		for _, method := range methods {
+			operations[method+" "+path] = true
+		}
+		return nil
+	})
+	return operations, err
+}
+
+func CheckRoutes(router *mux.Router) ([]string, error) {
+	documented, err := Operations()
+	if err != nil {
+		return nil, err
+	}
+	registered, err := RouterOperations(router)
+	if err != nil {
+		return nil, err
+	}
+	var problems []string
+	for operation := range registered {
+		if !documented[operation] {
+			problems = append(problems, fmt.Sprintf("%s is registered but not documented", operation))
+		}
+	}
+	for operation := range documented {
+		if !registered[operation] {
+			problems = append(problems, fmt.Sprintf("%s is documented but not registered", operation))
+		}
+	}
+	sort.Strings(problems)
+	return problems, nil
+}
@@ -0,0 +1,230 @@
+(function () {
+  "use strict";
+
+  var METHODS = ["get", "post", "put", "patch", "delete"];
+  var TOKEN_KEY = "docs.token";
+
+  function el(tag, attributes, children) {
+    var node = document.createElement(tag);
+    Object.keys(attributes || {}).forEach(function (name) {
+      if (name === "text") {
+        node.textContent = attributes[name];
+      } else {
+        node.setAttribute(name, attributes[name]);
+      }
+    });
+    (children || []).forEach(function (child) {
+      if (child) {
+        node.appendChild(child);
+      }
+    });
+    return node;
+  }
+
+  function resolve(spec, value) {
+    var seen = 0;
+    while (value && value.$ref && seen < 32) {
+      value = value.$ref.replace(/^#\//, "").split("/").reduce(function (node, key) {
+        return node && node[key];
+      }, spec);
+      seen++;
+    }
+    return value || {};
+  }
+
+  function example(spec, schema, depth) {
+    schema = resolve(spec, schema);
+    if (depth > 6) {
+      return null;
+    }
+    if (schema.example !== undefined) {
+      return schema.example;
+    }
+    if (schema.enum) {
+      return schema.enum[0];
+    }
+    if (schema.oneOf || schema.anyOf) {
+      return example(spec, (schema.oneOf || schema.anyOf)[0], depth + 1);
+    }
+    switch (schema.type) {
+      case "object":
+        var result = {};
+        Object.keys(schema.properties || {}).forEach(function (name) {
+          result[name] = example(spec, schema.properties[name], depth + 1);
+        });
+        return result;
+      case "array":
+        return [example(spec, schema.items, depth + 1)];
+      case "integer":
+      case "number":
+        return 0;
+      case "boolean":
+        return false;
+      case "string":
+        return schema.format === "date-time" ? new Date(0).toISOString() : "";
+    }
+    return null;
+  }
+
+  function requestSchema(spec, operation) {
+    var body = resolve(spec, operation.requestBody);
+    var content = body.content && body.content["application/json"];
+    return content ? content.schema : null;
+  }
+
+  function parametersOf(spec, item, operation) {
+    return (item.parameters || []).concat(operation.parameters || []).map(function (parameter) {
+      return resolve(spec, parameter);
+    });
+  }
+
+  function send(method, path, parameters, inputs, bodyInput, output) {
+    var query = new URLSearchParams();
+    var headers = { Accept: "application/json" };
+    var url = path;
+    parameters.forEach(function (parameter, index) {
+      var value = inputs[index].value;
+      if (value === "") {
+        return;
+      }
+      if (parameter.in === "path") {
+        url = url.replace("{" + parameter.name + "}", encodeURIComponent(value));
+      } else if (parameter.in === "query") {
+        query.set(parameter.name, value);
+      } else if (parameter.in === "header") {
+        headers[parameter.name] = value;
+      }
+    });
+    if (query.toString()) {
+      url += "?" + query.toString();
+    }
+    var token = document.getElementById("token").value;
+    if (token) {
+      headers.Authorization = "Bearer " + token;
+    }
+    var init = { method: method.toUpperCase(), headers: headers };
+    if (bodyInput && bodyInput.value.trim()) {
+      headers["Content-Type"] = "application/json";
+      init.body = bodyInput.value;
+    }
+    output.textContent = "Loading...";
+    fetch(url, init).then(function (response) {
+      return response.text().then(function (text) {
+        var lines = [response.status + " " + response.statusText];
+        response.headers.forEach(function (value, name) {
+          lines.push(name + ": " + value);
+        });
+        try {
+          text = JSON.stringify(JSON.parse(text), null, 2);
+        } catch (e) {}
+        output.textContent = lines.join("\n") + "\n\n" + text;
+      });
+    }).catch(function (error) {
+      output.textContent = String(error);
+    });
+  }
+
+  function renderOperation(spec, path, item, method, operation) {
+    var parameters = parametersOf(spec, item, operation);
+    var inputs = [];
+    var body = el("div", { "class": "body" });
+
+    if (operation.description) {
+      body.appendChild(el("p", { text: operation.description }));
+    }
+
+    if (parameters.length) {
+      var rows = parameters.map(function (parameter) {
+        var input = el("input", { type: "text", placeholder: parameter.in });
+        inputs.push(input);
+        return el("tr", {}, [
+          el("td", { text: parameter.name + (parameter.required ? " *" : "") }),
+          el("td", { text: parameter.in }),
+          el("td", {}, [input])
+        ]);
+      });
+      body.appendChild(el("h3", { text: "Parameters" }));
+      body.appendChild(el("table", {}, rows));
+    }
+
+    var schema = requestSchema(spec, operation);
+    var bodyInput = null;
+    if (schema) {
+      bodyInput = el("textarea", { spellcheck: "false" });
+      bodyInput.value = JSON.stringify(example(spec, schema, 0), null, 2);
+      body.appendChild(el("h3", { text: "Request body" }));
+      body.appendChild(bodyInput);
+    }
+
+    var responses = Object.keys(operation.responses || {}).map(function (status) {
+      var response = resolve(spec, operation.responses[status]);
+      return el("tr", {}, [
+        el("td", { "class": "status", text: status }),
+        el("td", { text: response.description || "" })
+      ]);
+    });
+    body.appendChild(el("h3", { text: "Responses" }));
+    body.appendChild(el("table", {}, responses));
+
+    var output = el("pre", {});
+    var button = el("button", { type: "button", text: "Send request" });
+    button.addEventListener("click", function () {
+      send(method, path, parameters, inputs, bodyInput, output);
+    });
+    body.appendChild(button);
+    body.appendChild(output);
+
+    return el("details", { "class": "operation" + (operation.deprecated ? " deprecated" : "") }, [
+      el("summary", {}, [
+        el("span", { "class": "method " + method, text: method }),
+        el("span", { "class": "path", text: path }),
+        el("span", { "class": "summary", text: operation.summary || "" })
+      ]),
+      body
+    ]);
+  }
+
+  function render(spec) {
+    document.title = spec.info.title;
+    document.getElementById("title").textContent = spec.info.title + " " + spec.info.version;
+    document.getElementById("description").textContent = spec.info.description || "";
+
+    var groups = {};
+    var order = [];
+    Object.keys(spec.paths).forEach(function (path) {
+      var item = spec.paths[path];
+      METHODS.forEach(function (method) {
+        var operation = item[method];
+        if (!operation) {
+          return;
+        }
+        var tag = (operation.tags && operation.tags[0]) || "default";
+        if (!groups[tag]) {
+          groups[tag] = [];
+          order.push(tag);
+        }
+        groups[tag].push(renderOperation(spec, path, item, method, operation));
+      });
+    });
+
+    var container = document.getElementById("operations");
+    order.forEach(function (tag) {
+      container.appendChild(el("h2", { text: tag }));
+      groups[tag].forEach(function (node) {
+        container.appendChild(node);
+      });
+    });
+  }
+
+  var token = document.getElementById("token");
+  token.value = window.localStorage.getItem(TOKEN_KEY) || "";
+  token.addEventListener("change", function () {
+    window.localStorage.setItem(TOKEN_KEY, token.value);
+  });
+
+  fetch("/openapi.json").then(function (response) {
+    return response.json();
+  }).then(render).catch(function (error) {
+    document.getElementById("operations").textContent = "Could not load /openapi.json: " + error;
+  });
+})();
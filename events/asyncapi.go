@@ -0,0 +1,134 @@
+package events
+
+import (
+	"reflect"
+	"strings"
+	"time"
+)
+
+const (
+	ASYNCAPI_VERSION = "2.6.0"
+	CHANNEL          = "/ws"
+)
+
+func AsyncAPI(host string, protocol string) map[string]interface{} {
+	messages := map[string]interface{}{}
+	schemas := map[string]interface{}{}
+	var refs []interface{}
+	for _, definition := range registry {
+		payload := reflect.TypeOf(definition.Payload)
+		schemas[payload.Name()] = schemaFor(payload)
+		messages[definition.Type] = map[string]interface{}{
+			"name":        definition.Type,
+			"title":       payload.Name(),
+			"summary":     definition.Summary,
+			"contentType": "application/json",
+			"payload": map[string]interface{}{
+				"type":     "object",
+				"required": []string{"type", "payload"},
+				"properties": map[string]interface{}{
+					"type": map[string]interface{}{
+						"type":  "string",
+						"const": definition.Type,
+					},
+					"payload": map[string]interface{}{
+						"$ref": "#/components/schemas/" + payload.Name(),
+					},
+				},
+			},
+		}
+		refs = append(refs, map[string]interface{}{
+			"$ref": "#/components/messages/" + definition.Type,
+		})
+	}
+
+	return map[string]interface{}{
+		"asyncapi": ASYNCAPI_VERSION,
+		"info": map[string]interface{}{
+			"title":       "rest-web-sockets-with-go events",
+			"version":     "1.0.0",
+			"description": "Events pushed to clients connected to the websocket endpoint. Every frame is a JSON object with a type and a payload.",
+		},
+		"defaultContentType": "application/json",
+		"servers": map[string]interface{}{
+			"default": map[string]interface{}{
+				"url":      host,
+				"protocol": protocol,
+				"security": []interface{}{
+					map[string]interface{}{"bearerAuth": []string{}},
+				},
+			},
+		},
+		"channels": map[string]interface{}{
+			CHANNEL: map[string]interface{}{
+				"description": "Authenticated event stream.",
+				"subscribe": map[string]interface{}{
+					"operationId": "receiveEvents",
+					"message": map[string]interface{}{
+						"oneOf": refs,
+					},
+				},
+			},
+		},
+		"components": map[string]interface{}{
+			"securitySchemes": map[string]interface{}{
+				"bearerAuth": map[string]interface{}{
+					"type":         "http",
+					"scheme":       "bearer",
+					"bearerFormat": "JWT",
+				},
+			},
+			"messages": messages,
+			"schemas":  schemas,
+		},
+	}
+}
+
+func schemaFor(t reflect.Type) map[string]interface{} {
+	if t == reflect.TypeOf(time.Time{}) {
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		return schemaFor(t.Elem())
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": schemaFor(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": schemaFor(t.Elem())}
+	case reflect.Struct:
+		properties := map[string]interface{}{}
+		required := []string{}
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name, options, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+			properties[name] = schemaFor(field.Type)
+			if !strings.Contains(options, "omitempty") && field.Type.Kind() != reflect.Pointer {
+				required = append(required, name)
+			}
+		}
+		schema := map[string]interface{}{"type": "object", "properties": properties}
+		if len(required) > 0 {
+			schema["required"] = required
+		}
+		return schema
+	}
+	return map[string]interface{}{}
+}
@@ -0,0 +1,62 @@
+package events
+
+import (
+	"time"
+
+	"github.com/th3khan/rest-web-sockets-with-go/models"
+)
+
+const (
+	EVENT_POST_CREATED = "post_created"
+)
+
+type Event interface {
+	EventType() string
+}
+
+type PostCreated struct {
+	ID        string    `json:"id"`
+	UserID    string    `json:"user_id"`
+	Title     string    `json:"title"`
+	Content   string    `json:"content"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (PostCreated) EventType() string {
+	return EVENT_POST_CREATED
+}
+
+func NewPostCreated(post *models.Post) PostCreated {
+	return PostCreated{
+		ID:        post.ID,
+		UserID:    post.UserID,
+		Title:     post.Title,
+		Content:   post.Content,
+		CreatedAt: post.CreatedAt,
+	}
+}
+
+type Definition struct {
+	Type    string
+	Summary string
+	Payload Event
+}
+
+var registry = []Definition{
+	{
+		Type:    EVENT_POST_CREATED,
+		Summary: "A post was created.",
+		Payload: PostCreated{},
+	},
+}
+
+func Definitions() []Definition {
+	return registry
+}
+
+func Message(event Event) models.WebsocketMessage {
+	return models.WebsocketMessage{
+		Type:    event.EventType(),
+		Payload: event,
+	}
+}
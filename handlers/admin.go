@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/th3khan/rest-web-sockets-with-go/models"
+	"github.com/th3khan/rest-web-sockets-with-go/problem"
+	"github.com/th3khan/rest-web-sockets-with-go/repositories"
+	"github.com/th3khan/rest-web-sockets-with-go/server"
+)
+
+var (
+	KNOWN_ROLES = []string{
+		models.ROLE_USER,
+		models.ROLE_MODERATOR,
+		models.ROLE_ADMIN,
+	}
+)
+
+type UpdateUserRolesRequest struct {
+	Roles []string `json:"roles"`
+}
+
+func isKnownRole(role string) bool {
+	for _, known := range KNOWN_ROLES {
+		if known == role {
+			return true
+		}
+	}
+	return false
+}
+
+func AdminListUsersHandler(s server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var err error
+		pageStr := r.URL.Query().Get("page")
+		var page uint64 = 0
+		if pageStr != "" {
+			page, err = strconv.ParseUint(pageStr, 10, 64)
+			if err != nil {
+				problem.Write(w, r, problem.New(http.StatusBadRequest, problem.CODE_VALIDATION_FAILED, "validation failed").WithErrors(map[string]string{"page": "must be a non-negative integer"}))
+				return
+			}
+		}
+		users, err := repositories.ListUsers(r.Context(), page)
+		if err != nil {
+			problem.Internal(w, r, err)
+			return
+		}
+		for _, user := range users {
+			user.Roles, err = repositories.GetUserRoles(r.Context(), user.ID)
+			if err != nil {
+				problem.Internal(w, r, err)
+				return
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(users)
+	}
+}
+
+func AdminGetUserHandler(s server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		params := mux.Vars(r)
+		user, err := repositories.GetUserById(r.Context(), params["id"])
+		if err != nil {
+			problem.Internal(w, r, err)
+			return
+		}
+		if user == nil || user.ID == "" {
+			problem.Error(w, r, http.StatusNotFound, problem.CODE_NOT_FOUND, "user not found")
+			return
+		}
+		user.Roles, err = repositories.GetUserRoles(r.Context(), user.ID)
+		if err != nil {
+			problem.Internal(w, r, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(user)
+	}
+}
+
+func AdminUpdateUserRolesHandler(s server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var request UpdateUserRolesRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			problem.MalformedBody(w, r, err)
+			return
+		}
+		roles := make([]string, 0, len(request.Roles))
+		seen := map[string]bool{}
+		for _, role := range request.Roles {
+			if !isKnownRole(role) {
+				problem.Error(w, r, http.StatusBadRequest, problem.CODE_BAD_REQUEST, "unknown role "+role)
+				return
+			}
+			if !seen[role] {
+				seen[role] = true
+				roles = append(roles, role)
+			}
+		}
+		params := mux.Vars(r)
+		user, err := repositories.GetUserById(r.Context(), params["id"])
+		if err != nil {
+			problem.Internal(w, r, err)
+			return
+		}
+		if user == nil || user.ID == "" {
+			problem.Error(w, r, http.StatusNotFound, problem.CODE_NOT_FOUND, "user not found")
+			return
+		}
+		if err := repositories.SetUserRoles(r.Context(), user.ID, roles); err != nil {
+			problem.Internal(w, r, err)
+			return
+		}
+		if err := s.Revocations().RevokeUser(r.Context(), user.ID); err != nil {
+			problem.Internal(w, r, err)
+			return
+		}
+		s.Hub().DisconnectUser(user.ID)
+		user.Roles = roles
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(user)
+	}
+}
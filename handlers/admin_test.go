@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/th3khan/rest-web-sockets-with-go/models"
+)
+
+func TestAdminUpdateUserRolesHandlerDeduplicatesRoles(t *testing.T) {
+	s := newTestServer(t)
+	repository := newFakeRepository(t)
+	repository.users["user"] = &models.User{ID: "user", Email: "user@example.com"}
+
+	r := httptest.NewRequest(http.MethodPut, "/admin/users/user/roles", strings.NewReader(`{"roles":["admin","admin","user"]}`))
+	r = mux.SetURLVars(r, map[string]string{"id": "user"})
+	recorder := httptest.NewRecorder()
+	AdminUpdateUserRolesHandler(s)(recorder, r)
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	if roles := repository.roles["user"]; !reflect.DeepEqual(roles, []string{models.ROLE_ADMIN, models.ROLE_USER}) {
+		t.Fatalf("expected deduplicated roles, got %v", roles)
+	}
+}
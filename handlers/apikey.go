@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/segmentio/ksuid"
+	"github.com/th3khan/rest-web-sockets-with-go/auth"
+	"github.com/th3khan/rest-web-sockets-with-go/models"
+	"github.com/th3khan/rest-web-sockets-with-go/problem"
+	"github.com/th3khan/rest-web-sockets-with-go/repositories"
+	"github.com/th3khan/rest-web-sockets-with-go/server"
+	"github.com/th3khan/rest-web-sockets-with-go/validation"
+)
+
+const (
+	API_KEY_NAME_MAX_LENGTH = 100
+)
+
+var (
+	KNOWN_SCOPES = []string{
+		models.SCOPE_POSTS_READ,
+		models.SCOPE_POSTS_WRITE,
+	}
+)
+
+type CreateAPIKeyRequest struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+}
+
+type CreateAPIKeyResponse struct {
+	Key    string         `json:"key"`
+	APIKey *models.APIKey `json:"api_key"`
+}
+
+func isKnownScope(scope string) bool {
+	for _, known := range KNOWN_SCOPES {
+		if known == scope {
+			return true
+		}
+	}
+	return false
+}
+
+func CreateAPIKeyHandler(s server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		principal, ok := auth.PrincipalFromContext(r.Context())
+		if !ok {
+			problem.Error(w, r, http.StatusUnauthorized, problem.CODE_UNAUTHORIZED, "authentication required")
+			return
+		}
+		var request CreateAPIKeyRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			problem.MalformedBody(w, r, err)
+			return
+		}
+		request.Name = strings.TrimSpace(request.Name)
+		errs := validation.Errors{}
+		validation.Required(errs, "name", request.Name)
+		if len(request.Name) > API_KEY_NAME_MAX_LENGTH {
+			errs.Add("name", "must be at most 100 characters")
+		}
+		if len(request.Scopes) == 0 {
+			errs.Add("scopes", "is required")
+		}
+		for _, scope := range request.Scopes {
+			if !isKnownScope(scope) {
+				errs.Add("scopes", "unknown scope "+scope)
+			}
+		}
+		if !errs.Empty() {
+			problem.Validation(w, r, http.StatusUnprocessableEntity, errs)
+			return
+		}
+
+		key, prefix, hash, err := auth.GenerateAPIKey()
+		if err != nil {
+			problem.Internal(w, r, err)
+			return
+		}
+		id, err := ksuid.NewRandom()
+		if err != nil {
+			problem.Internal(w, r, err)
+			return
+		}
+		apiKey := models.APIKey{
+			ID:      id.String(),
+			UserID:  principal.UserID,
+			Name:    request.Name,
+			Prefix:  prefix,
+			KeyHash: hash,
+			Scopes:  request.Scopes,
+		}
+		if err := repositories.InsertAPIKey(r.Context(), &apiKey); err != nil {
+			problem.Internal(w, r, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(CreateAPIKeyResponse{
+			Key:    key,
+			APIKey: &apiKey,
+		})
+	}
+}
+
+func ListAPIKeysHandler(s server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		principal, ok := auth.PrincipalFromContext(r.Context())
+		if !ok {
+			problem.Error(w, r, http.StatusUnauthorized, problem.CODE_UNAUTHORIZED, "authentication required")
+			return
+		}
+		keys, err := repositories.ListAPIKeys(r.Context(), principal.UserID)
+		if err != nil {
+			problem.Internal(w, r, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(keys)
+	}
+}
+
+func RevokeAPIKeyHandler(s server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		principal, ok := auth.PrincipalFromContext(r.Context())
+		if !ok {
+			problem.Error(w, r, http.StatusUnauthorized, problem.CODE_UNAUTHORIZED, "authentication required")
+			return
+		}
+		revoked, err := repositories.RevokeAPIKey(r.Context(), mux.Vars(r)["id"], principal.UserID)
+		if err != nil {
+			problem.Internal(w, r, err)
+			return
+		}
+		if !revoked {
+			problem.Error(w, r, http.StatusNotFound, problem.CODE_NOT_FOUND, "api key not found")
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
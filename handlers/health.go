@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/th3khan/rest-web-sockets-with-go/database"
+	"github.com/th3khan/rest-web-sockets-with-go/problem"
+	"github.com/th3khan/rest-web-sockets-with-go/repositories"
+	"github.com/th3khan/rest-web-sockets-with-go/server"
+)
+
+const (
+	STATUS_UP   = "up"
+	STATUS_DOWN = "down"
+)
+
+type ComponentStatus struct {
+	Status    string  `json:"status"`
+	LatencyMs float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+type HealthResponse struct {
+	Status     string                     `json:"status"`
+	Components map[string]ComponentStatus `json:"components,omitempty"`
+}
+
+func HealthzHandler(s server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(HealthResponse{
+			Status: STATUS_UP,
+		})
+	}
+}
+
+func ReadyzHandler(s server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+		defer cancel()
+
+		components := map[string]ComponentStatus{
+			"database": checkComponent(s, "database", func() error {
+				return repositories.Ping(ctx)
+			}),
+			"hub": checkComponent(s, "hub", func() error {
+				if !s.Hub().Running() {
+					return fmt.Errorf("hub is not running")
+				}
+				return nil
+			}),
+			"migrations": checkComponent(s, "migrations", func() error {
+				version, err := repositories.SchemaVersion(ctx)
+				if err != nil {
+					return err
+				}
+				if version < database.SCHEMA_VERSION {
+					return fmt.Errorf("schema version %d, expected %d", version, database.SCHEMA_VERSION)
+				}
+				return nil
+			}),
+		}
+
+		response := HealthResponse{
+			Status:     STATUS_UP,
+			Components: components,
+		}
+		status := http.StatusOK
+		for _, component := range components {
+			if component.Status != STATUS_UP {
+				response.Status = STATUS_DOWN
+				status = http.StatusServiceUnavailable
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+func checkComponent(s server.Server, name string, check func() error) ComponentStatus {
+	start := time.Now()
+	err := check()
+	component := ComponentStatus{
+		Status:    STATUS_UP,
+		LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		s.Logger().Warn("readiness check failed", "component", name, "error", err)
+		component.Status = STATUS_DOWN
+		component.Error = "unavailable"
+		if problem.ExposesInternal() {
+			component.Error = err.Error()
+		}
+	}
+	return component
+}
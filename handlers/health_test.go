@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/th3khan/rest-web-sockets-with-go/mailer"
+	"github.com/th3khan/rest-web-sockets-with-go/problem"
+	"github.com/th3khan/rest-web-sockets-with-go/server"
+)
+
+func TestReadyzHidesComponentErrorsInProduction(t *testing.T) {
+	repository := newFakeRepository(t)
+	repository.pingErr = errors.New("dial tcp db.internal:3306: access denied for user 'root'")
+	s, err := server.NewServer(context.Background(), &server.Config{
+		Port:        ":0",
+		DataBaseUrl: "test",
+		JWTSecret:   "test-secret",
+		Environment: server.ENVIRONMENT_PRODUCTION,
+		Mail:        mailer.Config{Driver: mailer.DRIVER_LOG},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { problem.SetExposeInternal(false) })
+
+	recorder := httptest.NewRecorder()
+	ReadyzHandler(s)(recorder, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+	if recorder.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503, got %d", recorder.Code)
+	}
+	if body := recorder.Body.String(); strings.Contains(body, "db.internal") || !strings.Contains(body, "unavailable") {
+		t.Fatalf("expected a generic component error, got %s", body)
+	}
+}
@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/th3khan/rest-web-sockets-with-go/problem"
+	"github.com/th3khan/rest-web-sockets-with-go/repositories"
+	"github.com/th3khan/rest-web-sockets-with-go/server"
+)
+
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func checkLoginAllowed(w http.ResponseWriter, r *http.Request, s server.Server, account string) bool {
+	decision, err := s.Lockout().Reserve(r.Context(), account, clientIP(r))
+	if err != nil {
+		problem.Internal(w, r, err)
+		return false
+	}
+	if decision.Allowed() {
+		return true
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(decision.RetryAfter.Seconds()))))
+	if decision.Status == http.StatusLocked {
+		problem.Error(w, r, http.StatusLocked, problem.CODE_ACCOUNT_LOCKED, "account temporarily locked")
+		return false
+	}
+	problem.Error(w, r, http.StatusTooManyRequests, problem.CODE_RATE_LIMITED, "too many failed login attempts")
+	return false
+}
+
+func recordLoginSuccess(r *http.Request, s server.Server, account string) {
+	if err := s.Lockout().Succeed(r.Context(), account, clientIP(r)); err != nil {
+		s.Logger().Error("could not reset failed logins", "error", err)
+	}
+}
+
+func AdminUnlockUserHandler(s server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, err := repositories.GetUserById(r.Context(), mux.Vars(r)["id"])
+		if err != nil {
+			problem.Internal(w, r, err)
+			return
+		}
+		if user == nil {
+			problem.Error(w, r, http.StatusNotFound, problem.CODE_NOT_FOUND, "user not found")
+			return
+		}
+		if err := s.Lockout().Unlock(r.Context(), user.Email); err != nil {
+			problem.Internal(w, r, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
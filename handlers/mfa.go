@@ -0,0 +1,312 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/th3khan/rest-web-sockets-with-go/auth"
+	"github.com/th3khan/rest-web-sockets-with-go/models"
+	"github.com/th3khan/rest-web-sockets-with-go/problem"
+	"github.com/th3khan/rest-web-sockets-with-go/repositories"
+	"github.com/th3khan/rest-web-sockets-with-go/server"
+	"github.com/th3khan/rest-web-sockets-with-go/totp"
+)
+
+const (
+	MFA_AUDIENCE        = "mfa"
+	RECOVERY_CODE_COUNT = 10
+)
+
+type MFAChallengeResponse struct {
+	MFARequired bool   `json:"mfa_required"`
+	MFAToken    string `json:"mfa_token"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+type TOTPEnrollmentResponse struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+type MFACodeRequest struct {
+	Code string `json:"code"`
+}
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type LoginMFARequest struct {
+	MFAToken string `json:"mfa_token"`
+	Code     string `json:"code"`
+}
+
+func issueMFAChallenge(s server.Server, userID string) (*MFAChallengeResponse, error) {
+	now := time.Now()
+	token, err := s.Keys().Sign(jwt.StandardClaims{
+		Subject:   userID,
+		Audience:  MFA_AUDIENCE,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(s.Config().MFAChallengeTTL).Unix(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &MFAChallengeResponse{
+		MFARequired: true,
+		MFAToken:    token,
+		ExpiresIn:   int64(s.Config().MFAChallengeTTL.Seconds()),
+	}, nil
+}
+
+func completeLogin(w http.ResponseWriter, r *http.Request, s server.Server, userID string) {
+	secret, err := repositories.GetTOTP(r.Context(), userID)
+	if err != nil {
+		problem.Internal(w, r, err)
+		return
+	}
+	if secret != nil && secret.ConfirmedAt != nil {
+		challenge, err := issueMFAChallenge(s, userID)
+		if err != nil {
+			problem.Internal(w, r, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(challenge)
+		return
+	}
+	response, _, err := issueTokens(r, s, userID, "")
+	if err != nil {
+		problem.Internal(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func generateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, RECOVERY_CODE_COUNT)
+	hashes := make([]string, RECOVERY_CODE_COUNT)
+	encoding := base32.StdEncoding.WithPadding(base32.NoPadding)
+	for i := range codes {
+		buf := make([]byte, 7)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, nil, err
+		}
+		code := strings.ToLower(encoding.EncodeToString(buf)[:10])
+		codes[i] = code[:5] + "-" + code[5:]
+		hashes[i] = hashToken(code)
+	}
+	return codes, hashes, nil
+}
+
+func verifyMFACode(ctx context.Context, secret *models.TOTP, code string) (bool, error) {
+	code = strings.TrimSpace(code)
+	if len(code) == totp.DIGITS {
+		step, ok := totp.Validate(secret.Secret, code, time.Now())
+		if !ok {
+			return false, nil
+		}
+		return repositories.UseTOTPStep(ctx, secret.UserID, step)
+	}
+	normalized := strings.ToLower(strings.ReplaceAll(code, "-", ""))
+	return repositories.UseRecoveryCode(ctx, secret.UserID, hashToken(normalized))
+}
+
+func EnrollTOTPHandler(s server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		principal, ok := auth.PrincipalFromContext(r.Context())
+		if !ok {
+			problem.Error(w, r, http.StatusUnauthorized, problem.CODE_UNAUTHORIZED, "authentication required")
+			return
+		}
+		existing, err := repositories.GetTOTP(r.Context(), principal.UserID)
+		if err != nil {
+			problem.Internal(w, r, err)
+			return
+		}
+		if existing != nil && existing.ConfirmedAt != nil {
+			problem.Error(w, r, http.StatusConflict, problem.CODE_CONFLICT, "two-factor authentication is already enabled")
+			return
+		}
+		user, err := repositories.GetUserById(r.Context(), principal.UserID)
+		if err != nil {
+			problem.Internal(w, r, err)
+			return
+		}
+		if user == nil {
+			problem.Error(w, r, http.StatusNotFound, problem.CODE_NOT_FOUND, "user not found")
+			return
+		}
+		secret, err := totp.GenerateSecret()
+		if err != nil {
+			problem.Internal(w, r, err)
+			return
+		}
+		if err := repositories.UpsertTOTP(r.Context(), &models.TOTP{UserID: user.ID, Secret: secret}); err != nil {
+			problem.Internal(w, r, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(TOTPEnrollmentResponse{
+			Secret:          secret,
+			ProvisioningURI: totp.ProvisioningURI(s.Config().MFAIssuer, user.Email, secret),
+		})
+	}
+}
+
+func ConfirmTOTPHandler(s server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		principal, ok := auth.PrincipalFromContext(r.Context())
+		if !ok {
+			problem.Error(w, r, http.StatusUnauthorized, problem.CODE_UNAUTHORIZED, "authentication required")
+			return
+		}
+		var request MFACodeRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			problem.MalformedBody(w, r, err)
+			return
+		}
+		secret, err := repositories.GetTOTP(r.Context(), principal.UserID)
+		if err != nil {
+			problem.Internal(w, r, err)
+			return
+		}
+		if secret == nil {
+			problem.Error(w, r, http.StatusNotFound, problem.CODE_NOT_FOUND, "two-factor enrollment not started")
+			return
+		}
+		if secret.ConfirmedAt != nil {
+			problem.Error(w, r, http.StatusConflict, problem.CODE_CONFLICT, "two-factor authentication is already enabled")
+			return
+		}
+		step, valid := totp.Validate(secret.Secret, strings.TrimSpace(request.Code), time.Now())
+		if !valid {
+			problem.Error(w, r, http.StatusBadRequest, problem.CODE_INVALID_CODE, "invalid code")
+			return
+		}
+		if _, err := repositories.UseTOTPStep(r.Context(), secret.UserID, step); err != nil {
+			problem.Internal(w, r, err)
+			return
+		}
+		codes, hashes, err := generateRecoveryCodes()
+		if err != nil {
+			problem.Internal(w, r, err)
+			return
+		}
+		if err := repositories.ReplaceRecoveryCodes(r.Context(), secret.UserID, hashes); err != nil {
+			problem.Internal(w, r, err)
+			return
+		}
+		if err := repositories.ConfirmTOTP(r.Context(), secret.UserID); err != nil {
+			problem.Internal(w, r, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(RecoveryCodesResponse{
+			RecoveryCodes: codes,
+		})
+	}
+}
+
+func DisableTOTPHandler(s server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		principal, ok := auth.PrincipalFromContext(r.Context())
+		if !ok {
+			problem.Error(w, r, http.StatusUnauthorized, problem.CODE_UNAUTHORIZED, "authentication required")
+			return
+		}
+		var request MFACodeRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			problem.MalformedBody(w, r, err)
+			return
+		}
+		secret, err := repositories.GetTOTP(r.Context(), principal.UserID)
+		if err != nil {
+			problem.Internal(w, r, err)
+			return
+		}
+		if secret == nil || secret.ConfirmedAt == nil {
+			problem.Error(w, r, http.StatusNotFound, problem.CODE_NOT_FOUND, "two-factor authentication is not enabled")
+			return
+		}
+		valid, err := verifyMFACode(r.Context(), secret, request.Code)
+		if err != nil {
+			problem.Internal(w, r, err)
+			return
+		}
+		if !valid {
+			problem.Error(w, r, http.StatusBadRequest, problem.CODE_INVALID_CODE, "invalid code")
+			return
+		}
+		if err := repositories.DeleteTOTP(r.Context(), secret.UserID); err != nil {
+			problem.Internal(w, r, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func LoginMFAHandler(s server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var request LoginMFARequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			problem.MalformedBody(w, r, err)
+			return
+		}
+		token, err := jwt.ParseWithClaims(request.MFAToken, &jwt.StandardClaims{}, s.Keys().Keyfunc)
+		if err != nil {
+			problem.Error(w, r, http.StatusUnauthorized, problem.CODE_INVALID_TOKEN, "invalid mfa token")
+			return
+		}
+		claims, ok := token.Claims.(*jwt.StandardClaims)
+		if !ok || !token.Valid || !claims.VerifyAudience(MFA_AUDIENCE, true) || claims.Subject == "" {
+			problem.Error(w, r, http.StatusUnauthorized, problem.CODE_INVALID_TOKEN, "invalid mfa token")
+			return
+		}
+		user, err := repositories.GetUserById(r.Context(), claims.Subject)
+		if err != nil {
+			problem.Internal(w, r, err)
+			return
+		}
+		if user == nil {
+			problem.Error(w, r, http.StatusUnauthorized, problem.CODE_INVALID_TOKEN, "invalid mfa token")
+			return
+		}
+		if !checkLoginAllowed(w, r, s, user.Email) {
+			return
+		}
+		secret, err := repositories.GetTOTP(r.Context(), claims.Subject)
+		if err != nil {
+			problem.Internal(w, r, err)
+			return
+		}
+		if secret == nil || secret.ConfirmedAt == nil {
+			problem.Error(w, r, http.StatusUnauthorized, problem.CODE_INVALID_TOKEN, "invalid mfa token")
+			return
+		}
+		valid, err := verifyMFACode(r.Context(), secret, request.Code)
+		if err != nil {
+			problem.Internal(w, r, err)
+			return
+		}
+		if !valid {
+			problem.Error(w, r, http.StatusUnauthorized, problem.CODE_INVALID_CODE, "invalid code")
+			return
+		}
+		recordLoginSuccess(r, s, user.Email)
+		response, _, err := issueTokens(r, s, claims.Subject, "")
+		if err != nil {
+			problem.Internal(w, r, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+	}
+}
@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/th3khan/rest-web-sockets-with-go/models"
+	"github.com/th3khan/rest-web-sockets-with-go/totp"
+)
+
+func TestVerifyMFACodeAcceptsSkew(t *testing.T) {
+	newFakeRepository(t)
+	secret := &models.TOTP{UserID: "user", Secret: "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"}
+	code, err := totp.Code(secret.Secret, totp.Step(time.Now())-totp.SKEW)
+	if err != nil {
+		t.Fatal(err)
+	}
+	valid, err := verifyMFACode(context.Background(), secret, code)
+	if err != nil || !valid {
+		t.Fatalf("expected previous step code to be accepted, got %v %v", valid, err)
+	}
+}
+
+func TestVerifyMFACodeRejectsReplay(t *testing.T) {
+	newFakeRepository(t)
+	secret := &models.TOTP{UserID: "user", Secret: "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"}
+	code, err := totp.Code(secret.Secret, totp.Step(time.Now()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if valid, err := verifyMFACode(context.Background(), secret, code); err != nil || !valid {
+		t.Fatalf("expected first use to be accepted, got %v %v", valid, err)
+	}
+	if valid, err := verifyMFACode(context.Background(), secret, code); err != nil || valid {
+		t.Fatalf("expected replay to be rejected, got %v %v", valid, err)
+	}
+	earlier, err := totp.Code(secret.Secret, totp.Step(time.Now())-totp.SKEW)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if valid, err := verifyMFACode(context.Background(), secret, earlier); err != nil || valid {
+		t.Fatalf("expected an older step to be rejected, got %v %v", valid, err)
+	}
+}
+
+func TestVerifyMFACodeConsumesRecoveryCode(t *testing.T) {
+	repository := newFakeRepository(t)
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	repository.ReplaceRecoveryCodes(context.Background(), "user", hashes)
+	secret := &models.TOTP{UserID: "user", Secret: "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"}
+
+	if valid, err := verifyMFACode(context.Background(), secret, " "+strings.ToUpper(codes[0])+" "); err != nil || !valid {
+		t.Fatalf("expected recovery code to be accepted, got %v %v", valid, err)
+	}
+	if valid, err := verifyMFACode(context.Background(), secret, codes[0]); err != nil || valid {
+		t.Fatalf("expected used recovery code to be rejected, got %v %v", valid, err)
+	}
+	if valid, err := verifyMFACode(context.Background(), secret, strings.ReplaceAll(codes[1], "-", "")); err != nil || !valid {
+		t.Fatalf("expected another recovery code to be accepted, got %v %v", valid, err)
+	}
+	if valid, err := verifyMFACode(context.Background(), secret, "aaaaa-aaaaa"); err != nil || valid {
+		t.Fatalf("expected unknown recovery code to be rejected, got %v %v", valid, err)
+	}
+}
+
+func TestLoginMFAHandlerChecksAudience(t *testing.T) {
+	s := newTestServer(t)
+	now := time.Now()
+	tests := []struct {
+		name    string
+		claims  jwt.StandardClaims
+		allowed bool
+	}{
+		{"mfa challenge", jwt.StandardClaims{Subject: "user", Audience: MFA_AUDIENCE, ExpiresAt: now.Add(time.Minute).Unix()}, true},
+		{"other audience", jwt.StandardClaims{Subject: "user", Audience: "api", ExpiresAt: now.Add(time.Minute).Unix()}, false},
+		{"missing audience", jwt.StandardClaims{Subject: "user", ExpiresAt: now.Add(time.Minute).Unix()}, false},
+		{"missing subject", jwt.StandardClaims{Audience: MFA_AUDIENCE, ExpiresAt: now.Add(time.Minute).Unix()}, false},
+		{"expired", jwt.StandardClaims{Subject: "user", Audience: MFA_AUDIENCE, ExpiresAt: now.Add(-time.Minute).Unix()}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			repository := newFakeRepository(t)
+			token, err := s.Keys().Sign(test.claims)
+			if err != nil {
+				t.Fatal(err)
+			}
+			body := `{"mfa_token":"` + token + `","code":"000000"}`
+			recorder := httptest.NewRecorder()
+			LoginMFAHandler(s)(recorder, httptest.NewRequest(http.MethodPost, "/login/mfa", strings.NewReader(body)))
+
+			if recorder.Code != http.StatusUnauthorized {
+				t.Fatalf("expected 401, got %d", recorder.Code)
+			}
+			if allowed := repository.lookups > 0; allowed != test.allowed {
+				t.Fatalf("expected token accepted to be %v, got %v", test.allowed, allowed)
+			}
+		})
+	}
+}
@@ -0,0 +1,288 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/segmentio/ksuid"
+	"github.com/th3khan/rest-web-sockets-with-go/auth"
+	"github.com/th3khan/rest-web-sockets-with-go/models"
+	"github.com/th3khan/rest-web-sockets-with-go/oidc"
+	"github.com/th3khan/rest-web-sockets-with-go/problem"
+	"github.com/th3khan/rest-web-sockets-with-go/repositories"
+	"github.com/th3khan/rest-web-sockets-with-go/server"
+	"github.com/th3khan/rest-web-sockets-with-go/validation"
+)
+
+const (
+	OIDC_STATE_AUDIENCE = "oidc-state"
+	OIDC_STATE_COOKIE   = "oidc_state"
+	OIDC_STATE_TTL      = 10 * time.Minute
+)
+
+type OIDCLinkResponse struct {
+	AuthorizationURL string `json:"authorization_url"`
+}
+
+func setOIDCStateCookie(w http.ResponseWriter, s server.Server, value string, maxAge int) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     OIDC_STATE_COOKIE,
+		Value:    value,
+		Path:     "/",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   strings.HasPrefix(s.Config().AppBaseURL, "https://"),
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func readOIDCState(r *http.Request, s server.Server) (*models.OIDCStateClaims, error) {
+	cookie, err := r.Cookie(OIDC_STATE_COOKIE)
+	if err != nil {
+		return nil, err
+	}
+	token, err := jwt.ParseWithClaims(cookie.Value, &models.OIDCStateClaims{}, s.Keys().Keyfunc)
+	if err != nil {
+		return nil, err
+	}
+	claims, ok := token.Claims.(*models.OIDCStateClaims)
+	if !ok || !token.Valid || !claims.VerifyAudience(OIDC_STATE_AUDIENCE, true) {
+		return nil, errors.New("invalid state")
+	}
+	return claims, nil
+}
+
+func insertExternalIdentity(ctx context.Context, provider *oidc.Provider, claims *oidc.IDTokenClaims, userID string) (*models.ExternalIdentity, error) {
+	id, err := ksuid.NewRandom()
+	if err != nil {
+		return nil, err
+	}
+	identity := &models.ExternalIdentity{
+		ID:        id.String(),
+		UserID:    userID,
+		Provider:  provider.Issuer(),
+		Subject:   claims.Subject,
+		Email:     validation.NormalizeEmail(claims.Email),
+		CreatedAt: time.Now(),
+	}
+	err = repositories.InsertExternalIdentity(ctx, identity)
+	if errors.Is(err, repositories.ErrDuplicate) {
+		return nil, problem.New(http.StatusConflict, problem.CODE_CONFLICT, "identity is already linked")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return identity, nil
+}
+
+func linkExternalIdentity(ctx context.Context, provider *oidc.Provider, claims *oidc.IDTokenClaims) (*models.User, error) {
+	identity, err := repositories.GetExternalIdentity(ctx, provider.Issuer(), claims.Subject)
+	if err != nil {
+		return nil, err
+	}
+	if identity != nil {
+		user, err := repositories.GetUserById(ctx, identity.UserID)
+		if err != nil {
+			return nil, err
+		}
+		if user == nil {
+			return nil, problem.New(http.StatusUnauthorized, problem.CODE_UNAUTHORIZED, "linked user no longer exists")
+		}
+		return user, nil
+	}
+
+	email := validation.NormalizeEmail(claims.Email)
+	if email == "" {
+		return nil, problem.New(http.StatusBadRequest, problem.CODE_BAD_REQUEST, "identity provider did not return an email address")
+	}
+	user, err := repositories.GetUserByEmail(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+	if user != nil && (!claims.EmailVerified || !user.EmailVerified) {
+		return nil, problem.New(http.StatusConflict, problem.CODE_CONFLICT, "an account with this email already exists; sign in and link this provider from your account")
+	}
+	if user == nil {
+		id, err := ksuid.NewRandom()
+		if err != nil {
+			return nil, err
+		}
+		user = &models.User{
+			ID:    id.String(),
+			Email: email,
+		}
+		if err := repositories.InsertUser(ctx, user); err != nil {
+			return nil, err
+		}
+		if err := repositories.SetUserRoles(ctx, user.ID, []string{models.ROLE_USER}); err != nil {
+			return nil, err
+		}
+		if claims.EmailVerified {
+			if err := repositories.MarkEmailVerified(ctx, user.ID); err != nil {
+				return nil, err
+			}
+			user.EmailVerified = true
+		}
+	}
+
+	if _, err := insertExternalIdentity(ctx, provider, claims, user.ID); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func attachExternalIdentity(ctx context.Context, provider *oidc.Provider, claims *oidc.IDTokenClaims, userID string) (*models.ExternalIdentity, error) {
+	identity, err := repositories.GetExternalIdentity(ctx, provider.Issuer(), claims.Subject)
+	if err != nil {
+		return nil, err
+	}
+	if identity != nil {
+		if identity.UserID != userID {
+			return nil, problem.New(http.StatusConflict, problem.CODE_CONFLICT, "identity is already linked to another account")
+		}
+		return identity, nil
+	}
+	return insertExternalIdentity(ctx, provider, claims, userID)
+}
+
+func beginOIDCFlow(w http.ResponseWriter, r *http.Request, s server.Server, provider *oidc.Provider, userID string) (string, error) {
+	state, err := generateOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+	nonce, err := generateOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+	verifier, err := oidc.NewCodeVerifier()
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
+	cookie, err := s.Keys().Sign(models.OIDCStateClaims{
+		State:      state,
+		Nonce:      nonce,
+		Verifier:   verifier,
+		LinkUserID: userID,
+		StandardClaims: jwt.StandardClaims{
+			Audience:  OIDC_STATE_AUDIENCE,
+			IssuedAt:  now.Unix(),
+			ExpiresAt: now.Add(OIDC_STATE_TTL).Unix(),
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+	location, err := provider.AuthCodeURL(r.Context(), state, nonce, verifier)
+	if err != nil {
+		return "", err
+	}
+	setOIDCStateCookie(w, s, cookie, int(OIDC_STATE_TTL.Seconds()))
+	return location, nil
+}
+
+func OIDCLoginHandler(s server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		provider := s.OIDC()
+		if provider == nil {
+			problem.Error(w, r, http.StatusNotFound, problem.CODE_NOT_FOUND, "social login is not configured")
+			return
+		}
+		location, err := beginOIDCFlow(w, r, s, provider, "")
+		if err != nil {
+			s.Logger().Error("could not build authorization url", "error", err)
+			problem.Error(w, r, http.StatusBadGateway, problem.CODE_UPSTREAM_FAILED, "identity provider unavailable")
+			return
+		}
+		http.Redirect(w, r, location, http.StatusFound)
+	}
+}
+
+func OIDCLinkHandler(s server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		principal, ok := auth.PrincipalFromContext(r.Context())
+		if !ok {
+			problem.Error(w, r, http.StatusUnauthorized, problem.CODE_UNAUTHORIZED, "authentication required")
+			return
+		}
+		provider := s.OIDC()
+		if provider == nil {
+			problem.Error(w, r, http.StatusNotFound, problem.CODE_NOT_FOUND, "social login is not configured")
+			return
+		}
+		location, err := beginOIDCFlow(w, r, s, provider, principal.UserID)
+		if err != nil {
+			s.Logger().Error("could not build authorization url", "error", err)
+			problem.Error(w, r, http.StatusBadGateway, problem.CODE_UPSTREAM_FAILED, "identity provider unavailable")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(OIDCLinkResponse{
+			AuthorizationURL: location,
+		})
+	}
+}
+
+func OIDCCallbackHandler(s server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		provider := s.OIDC()
+		if provider == nil {
+			problem.Error(w, r, http.StatusNotFound, problem.CODE_NOT_FOUND, "social login is not configured")
+			return
+		}
+		query := r.URL.Query()
+		if providerError := query.Get("error"); providerError != "" {
+			problem.Error(w, r, http.StatusBadRequest, problem.CODE_BAD_REQUEST, "authorization failed: "+providerError)
+			return
+		}
+		state, err := readOIDCState(r, s)
+		if err != nil || query.Get("state") == "" || query.Get("state") != state.State {
+			problem.Error(w, r, http.StatusBadRequest, problem.CODE_INVALID_TOKEN, "invalid state")
+			return
+		}
+		setOIDCStateCookie(w, s, "", -1)
+		code := query.Get("code")
+		if code == "" {
+			problem.Error(w, r, http.StatusBadRequest, problem.CODE_BAD_REQUEST, "missing authorization code")
+			return
+		}
+		token, err := provider.Exchange(r.Context(), code, state.Verifier)
+		if err != nil {
+			s.Logger().Warn("authorization code exchange failed", "error", err)
+			problem.Error(w, r, http.StatusBadGateway, problem.CODE_UPSTREAM_FAILED, "authorization code exchange failed")
+			return
+		}
+		claims, err := provider.VerifyIDToken(r.Context(), token.IDToken, state.Nonce)
+		if err != nil {
+			s.Logger().Warn("id token rejected", "error", err)
+			problem.Error(w, r, http.StatusUnauthorized, problem.CODE_INVALID_TOKEN, "invalid id token")
+			return
+		}
+		if state.LinkUserID != "" {
+			identity, err := attachExternalIdentity(r.Context(), provider, claims, state.LinkUserID)
+			if err != nil {
+				problem.WriteError(w, r, err)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(identity)
+			return
+		}
+		user, err := linkExternalIdentity(r.Context(), provider, claims)
+		if err != nil {
+			problem.WriteError(w, r, err)
+			return
+		}
+		if s.Config().RequireVerifiedEmail == REQUIRE_VERIFIED_LOGIN && !user.EmailVerified {
+			problem.Error(w, r, http.StatusForbidden, problem.CODE_EMAIL_NOT_VERIFIED, "email address not verified")
+			return
+		}
+		completeLogin(w, r, s, user.ID)
+	}
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/th3khan/rest-web-sockets-with-go/models"
+	"github.com/th3khan/rest-web-sockets-with-go/oidc"
+	"github.com/th3khan/rest-web-sockets-with-go/problem"
+)
+
+func newTestProvider(t *testing.T) *oidc.Provider {
+	provider, err := oidc.NewProvider(&oidc.Config{
+		IssuerURL:   "https://issuer.example.com",
+		ClientID:    "client",
+		RedirectURL: "http://localhost/callback",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return provider
+}
+
+func TestLinkExternalIdentityRequiresVerifiedEmails(t *testing.T) {
+	tests := []struct {
+		name          string
+		userVerified  bool
+		claimVerified bool
+		linked        bool
+	}{
+		{"both verified", true, true, true},
+		{"local account unverified", false, true, false},
+		{"provider email unverified", true, false, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			repository := newFakeRepository(t)
+			repository.users["user"] = &models.User{ID: "user", Email: "user@example.com", EmailVerified: test.userVerified}
+			provider := newTestProvider(t)
+			claims := &oidc.IDTokenClaims{Email: "User@Example.com", EmailVerified: test.claimVerified}
+			claims.Subject = "subject"
+
+			user, err := linkExternalIdentity(context.Background(), provider, claims)
+			if test.linked {
+				if err != nil || user.ID != "user" {
+					t.Fatalf("expected identity to be linked to user, got %v %v", user, err)
+				}
+				if repository.identities[provider.Issuer()+" subject"] == nil {
+					t.Fatal("expected identity to be stored")
+				}
+				return
+			}
+			var details *problem.Problem
+			if !errors.As(err, &details) || details.Status != http.StatusConflict {
+				t.Fatalf("expected 409, got %v", err)
+			}
+			if len(repository.identities) != 0 {
+				t.Fatal("expected no identity to be stored")
+			}
+		})
+	}
+}
+
+func TestAttachExternalIdentityRejectsOtherAccount(t *testing.T) {
+	repository := newFakeRepository(t)
+	provider := newTestProvider(t)
+	claims := &oidc.IDTokenClaims{Email: "user@example.com"}
+	claims.Subject = "subject"
+
+	identity, err := attachExternalIdentity(context.Background(), provider, claims, "user")
+	if err != nil || identity.UserID != "user" {
+		t.Fatalf("expected identity to be linked, got %v %v", identity, err)
+	}
+	if _, err := attachExternalIdentity(context.Background(), provider, claims, "user"); err != nil {
+		t.Fatalf("expected relinking the same account to succeed, got %v", err)
+	}
+	var details *problem.Problem
+	if _, err := attachExternalIdentity(context.Background(), provider, claims, "other"); !errors.As(err, &details) || details.Status != http.StatusConflict {
+		t.Fatalf("expected 409, got %v", err)
+	}
+	if len(repository.identities) != 1 {
+		t.Fatalf("expected one stored identity, got %d", len(repository.identities))
+	}
+}
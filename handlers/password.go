@@ -0,0 +1,228 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/segmentio/ksuid"
+	"github.com/th3khan/rest-web-sockets-with-go/auth"
+	"github.com/th3khan/rest-web-sockets-with-go/mailer"
+	"github.com/th3khan/rest-web-sockets-with-go/models"
+	"github.com/th3khan/rest-web-sockets-with-go/problem"
+	"github.com/th3khan/rest-web-sockets-with-go/repositories"
+	"github.com/th3khan/rest-web-sockets-with-go/server"
+	"github.com/th3khan/rest-web-sockets-with-go/validation"
+)
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+type PasswordResponse struct {
+	Message string `json:"message"`
+}
+
+func rehashPassword(ctx context.Context, s server.Server, userID string, password string) {
+	hashedPassword, err := s.Passwords().Hash(password)
+	if err == nil {
+		err = repositories.UpdateUserPassword(ctx, userID, hashedPassword)
+	}
+	if err != nil {
+		s.Logger().Error("could not rehash password", "user_id", userID, "error", err)
+	}
+}
+
+func sendPasswordResetEmail(ctx context.Context, s server.Server, user *models.User) error {
+	token, err := generateOpaqueToken()
+	if err != nil {
+		return err
+	}
+	id, err := ksuid.NewRandom()
+	if err != nil {
+		return err
+	}
+	err = repositories.InsertPasswordResetToken(ctx, &models.PasswordResetToken{
+		ID:        id.String(),
+		UserID:    user.ID,
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().Add(s.Config().PasswordResetTTL),
+	})
+	if err != nil {
+		return err
+	}
+	link := fmt.Sprintf("%s/password/reset?token=%s", s.Config().AppBaseURL, url.QueryEscape(token))
+	return s.Mailer().Send(ctx, &mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body:    fmt.Sprintf("Someone requested a password reset for your account. If it was you, open the following link:\n\n%s\n\nThe link expires in %s. If you did not request it, ignore this email.\n", link, s.Config().PasswordResetTTL),
+	})
+}
+
+func ForgotPasswordHandler(s server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var request ForgotPasswordRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			problem.MalformedBody(w, r, err)
+			return
+		}
+		request.Email = validation.NormalizeEmail(request.Email)
+		errs := validation.Errors{}
+		validation.ValidateEmail(errs, "email", request.Email)
+		if !errs.Empty() {
+			problem.Validation(w, r, http.StatusUnprocessableEntity, errs)
+			return
+		}
+
+		ctx := context.WithoutCancel(r.Context())
+		go func() {
+			user, err := repositories.GetUserByEmail(ctx, request.Email)
+			if err != nil {
+				s.Logger().Error("could not look up user for password reset", "error", err)
+				return
+			}
+			if user == nil {
+				return
+			}
+			if err := sendPasswordResetEmail(ctx, s, user); err != nil {
+				s.Logger().Error("could not send password reset email", "user_id", user.ID, "error", err)
+			}
+		}()
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(PasswordResponse{
+			Message: "If the email is registered, a reset link has been sent",
+		})
+	}
+}
+
+func ResetPasswordHandler(s server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var request ResetPasswordRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			problem.MalformedBody(w, r, err)
+			return
+		}
+		errs := validation.Errors{}
+		validation.Required(errs, "token", request.Token)
+		validatePassword(s, errs, "password", request.Password)
+		if !errs.Empty() {
+			problem.Validation(w, r, http.StatusUnprocessableEntity, errs)
+			return
+		}
+		token, err := repositories.GetPasswordResetTokenByHash(r.Context(), hashToken(request.Token))
+		if err != nil {
+			problem.Internal(w, r, err)
+			return
+		}
+		if token == nil || token.UsedAt != nil || time.Now().After(token.ExpiresAt) {
+			problem.Error(w, r, http.StatusBadRequest, problem.CODE_INVALID_TOKEN, "invalid or expired reset token")
+			return
+		}
+
+		hashedPassword, err := s.Passwords().Hash(request.Password)
+		if err != nil {
+			problem.Internal(w, r, err)
+			return
+		}
+		used, err := repositories.MarkPasswordResetTokenUsed(r.Context(), token.ID)
+		if err != nil {
+			problem.Internal(w, r, err)
+			return
+		}
+		if !used {
+			problem.Error(w, r, http.StatusBadRequest, problem.CODE_INVALID_TOKEN, "invalid or expired reset token")
+			return
+		}
+		if err := repositories.InvalidatePasswordResetTokens(r.Context(), token.UserID); err != nil {
+			problem.Internal(w, r, err)
+			return
+		}
+		if err := repositories.UpdateUserPassword(r.Context(), token.UserID, hashedPassword); err != nil {
+			problem.Internal(w, r, err)
+			return
+		}
+		if err := s.Revocations().RevokeUser(r.Context(), token.UserID); err != nil {
+			problem.Internal(w, r, err)
+			return
+		}
+		s.Hub().DisconnectUser(token.UserID)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(PasswordResponse{
+			Message: "Password has been reset",
+		})
+	}
+}
+
+func ChangePasswordHandler(s server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		principal, ok := auth.PrincipalFromContext(r.Context())
+		if !ok {
+			problem.Error(w, r, http.StatusUnauthorized, problem.CODE_UNAUTHORIZED, "authentication required")
+			return
+		}
+		var request ChangePasswordRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			problem.MalformedBody(w, r, err)
+			return
+		}
+		errs := validation.Errors{}
+		validation.Required(errs, "current_password", request.CurrentPassword)
+		validatePassword(s, errs, "new_password", request.NewPassword)
+		if !errs.Empty() {
+			problem.Validation(w, r, http.StatusUnprocessableEntity, errs)
+			return
+		}
+		user, err := repositories.GetUserById(r.Context(), principal.UserID)
+		if err != nil {
+			problem.Internal(w, r, err)
+			return
+		}
+		if user == nil {
+			problem.Error(w, r, http.StatusNotFound, problem.CODE_NOT_FOUND, "user not found")
+			return
+		}
+		valid, err := s.Passwords().Verify(user.Password, request.CurrentPassword)
+		if err != nil || !valid {
+			problem.Error(w, r, http.StatusForbidden, problem.CODE_INVALID_CREDENTIALS, "invalid current password")
+			return
+		}
+		hashedPassword, err := s.Passwords().Hash(request.NewPassword)
+		if err != nil {
+			problem.Internal(w, r, err)
+			return
+		}
+		if err := repositories.UpdateUserPassword(r.Context(), user.ID, hashedPassword); err != nil {
+			problem.Internal(w, r, err)
+			return
+		}
+		if err := s.Revocations().RevokeUser(r.Context(), user.ID); err != nil {
+			problem.Internal(w, r, err)
+			return
+		}
+		s.Hub().DisconnectUser(user.ID)
+
+		response, _, err := issueTokens(r, s, user.ID, "")
+		if err != nil {
+			problem.Internal(w, r, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+	}
+}
@@ -1,15 +1,19 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
-	"strings"
+	"time"
 
-	"github.com/golang-jwt/jwt"
 	"github.com/gorilla/mux"
 	"github.com/segmentio/ksuid"
+	"github.com/th3khan/rest-web-sockets-with-go/auth"
+	"github.com/th3khan/rest-web-sockets-with-go/events"
 	"github.com/th3khan/rest-web-sockets-with-go/models"
+	"github.com/th3khan/rest-web-sockets-with-go/problem"
 	"github.com/th3khan/rest-web-sockets-with-go/repositories"
 	"github.com/th3khan/rest-web-sockets-with-go/server"
 )
@@ -32,55 +36,49 @@ type UpdatedPostResponse struct {
 
 func InsertPostHandler(s server.Server) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		tokenString := strings.TrimSpace(r.Header.Get("Authorization"))
-		token, err := jwt.ParseWithClaims(tokenString, &models.AppClaims{}, func(token *jwt.Token) (interface{}, error) {
-			return []byte(s.Config().JWTSecret), nil
-		})
+		principal, ok := auth.PrincipalFromContext(r.Context())
+		if !ok {
+			problem.Error(w, r, http.StatusUnauthorized, problem.CODE_UNAUTHORIZED, "authentication required")
+			return
+		}
+		if err := requireVerifiedPosting(r.Context(), s, principal); err != nil {
+			problem.WriteError(w, r, err)
+			return
+		}
+		var postRequest UpsertPostRequest
+		if err := json.NewDecoder(r.Body).Decode(&postRequest); err != nil {
+			problem.MalformedBody(w, r, err)
+			return
+		}
+		id, err := ksuid.NewRandom()
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusUnauthorized)
+			problem.Internal(w, r, err)
+			return
+		}
+		post := models.Post{
+			ID:        id.String(),
+			UserID:    principal.UserID,
+			Title:     postRequest.Title,
+			Content:   postRequest.Content,
+			CreatedAt: time.Now().UTC().Truncate(time.Second),
+		}
+		err = repositories.InsertPost(r.Context(), &post)
+		if err != nil {
+			problem.Internal(w, r, err)
 			return
 		}
-		if claims, ok := token.Claims.(*models.AppClaims); ok && token.Valid {
-			var postRequest UpsertPostRequest
-			if err := json.NewDecoder(r.Body).Decode(&postRequest); err != nil {
-				http.Error(w, err.Error(), http.StatusBadRequest)
-				return
-			}
-			id, err := ksuid.NewRandom()
-			if err != nil {
-				http.Error(w, err.Error(), http.StatusInternalServerError)
-				return
-			}
-			post := models.Post{
-				ID:      id.String(),
-				UserID:  claims.UserID,
-				Title:   postRequest.Title,
-				Content: postRequest.Content,
-			}
-			err = repositories.InsertPost(r.Context(), &post)
-			if err != nil {
-				http.Error(w, err.Error(), http.StatusInternalServerError)
-				return
-			}
 
-			var postMessage = models.WebsocketMessage{
-				Type:    "post_created",
-				Payload: post,
-			}
-			s.Hub().Broadcast(postMessage, nil)
+		s.Hub().Broadcast(r.Context(), events.Message(events.NewPostCreated(&post)), nil)
 
-			w.WriteHeader(http.StatusCreated)
-			w.Header().Set("Content-Type", "application/json")
-			postResponse := PostResponse{
-				ID:      post.ID,
-				UserID:  post.UserID,
-				Title:   post.Title,
-				Content: post.Content,
-			}
-			json.NewEncoder(w).Encode(postResponse)
-		} else {
-			http.Error(w, err.Error(), http.StatusUnauthorized)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		postResponse := PostResponse{
+			ID:      post.ID,
+			UserID:  post.UserID,
+			Title:   post.Title,
+			Content: post.Content,
 		}
+		json.NewEncoder(w).Encode(postResponse)
 	}
 }
 
@@ -89,91 +87,118 @@ func GetPostByIdHandler(s server.Server) http.HandlerFunc {
 		params := mux.Vars(r)
 		post, err := repositories.GetPostById(r.Context(), params["id"])
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			problem.Internal(w, r, err)
+			return
+		}
+		if post == nil || post.ID == "" {
+			problem.Error(w, r, http.StatusNotFound, problem.CODE_NOT_FOUND, "post not found")
+			return
 		}
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(post)
 	}
 }
 
+func postOwnerID(ctx context.Context, principal *auth.Principal, id string, permission string) (string, error) {
+	if !principal.HasPermission(permission) {
+		return principal.UserID, nil
+	}
+	post, err := repositories.GetPostById(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	if post == nil || post.ID == "" {
+		return "", problem.New(http.StatusNotFound, problem.CODE_NOT_FOUND, "post not found")
+	}
+	return post.UserID, nil
+}
+
 func UpdatePostHandler(s server.Server) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		tokenString := strings.TrimSpace(r.Header.Get("Authorization"))
-		token, err := jwt.ParseWithClaims(tokenString, &models.AppClaims{}, func(token *jwt.Token) (interface{}, error) {
-			return []byte(s.Config().JWTSecret), nil
-		})
+		principal, ok := auth.PrincipalFromContext(r.Context())
+		if !ok {
+			problem.Error(w, r, http.StatusUnauthorized, problem.CODE_UNAUTHORIZED, "authentication required")
+			return
+		}
+		if err := requireVerifiedPosting(r.Context(), s, principal); err != nil {
+			problem.WriteError(w, r, err)
+			return
+		}
+		var postRequest UpsertPostRequest
+		if err := json.NewDecoder(r.Body).Decode(&postRequest); err != nil {
+			problem.MalformedBody(w, r, err)
+			return
+		}
+		params := mux.Vars(r)
+		id := params["id"]
+		if id == "" {
+			problem.Error(w, r, http.StatusBadRequest, problem.CODE_BAD_REQUEST, "missing post id")
+			return
+		}
+		ownerID, err := postOwnerID(r.Context(), principal, id, models.PERMISSION_POSTS_EDIT_ANY)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusUnauthorized)
+			problem.WriteError(w, r, err)
 			return
 		}
-		if claims, ok := token.Claims.(*models.AppClaims); ok && token.Valid {
-			var postRequest UpsertPostRequest
-			if err := json.NewDecoder(r.Body).Decode(&postRequest); err != nil {
-				http.Error(w, err.Error(), http.StatusBadRequest)
-				return
-			}
-			params := mux.Vars(r)
-			id := params["id"]
-			if id == "" {
-				http.Error(w, err.Error(), http.StatusBadRequest)
-				return
-			}
-			post := models.Post{
-				ID:      id,
-				UserID:  claims.UserID,
-				Title:   postRequest.Title,
-				Content: postRequest.Content,
-			}
-			err = repositories.UpdatePost(r.Context(), &post)
-			if err != nil {
-				http.Error(w, err.Error(), http.StatusInternalServerError)
-				return
-			}
-			w.WriteHeader(http.StatusOK)
-			w.Header().Set("Content-Type", "application/json")
-			postResponse := PostResponse{
-				ID:      post.ID,
-				UserID:  post.UserID,
-				Title:   post.Title,
-				Content: post.Content,
-			}
-			json.NewEncoder(w).Encode(postResponse)
-		} else {
-			http.Error(w, err.Error(), http.StatusUnauthorized)
+		post := models.Post{
+			ID:      id,
+			UserID:  ownerID,
+			Title:   postRequest.Title,
+			Content: postRequest.Content,
+		}
+		err = repositories.UpdatePost(r.Context(), &post)
+		if errors.Is(err, repositories.ErrNotFound) {
+			problem.Error(w, r, http.StatusNotFound, problem.CODE_NOT_FOUND, "post not found")
+			return
 		}
+		if err != nil {
+			problem.Internal(w, r, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		postResponse := PostResponse{
+			ID:      post.ID,
+			UserID:  post.UserID,
+			Title:   post.Title,
+			Content: post.Content,
+		}
+		json.NewEncoder(w).Encode(postResponse)
 	}
 }
 
 func DeletePostHandler(s server.Server) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		tokenString := strings.TrimSpace(r.Header.Get("Authorization"))
-		token, err := jwt.ParseWithClaims(tokenString, &models.AppClaims{}, func(token *jwt.Token) (interface{}, error) {
-			return []byte(s.Config().JWTSecret), nil
-		})
+		principal, ok := auth.PrincipalFromContext(r.Context())
+		if !ok {
+			problem.Error(w, r, http.StatusUnauthorized, problem.CODE_UNAUTHORIZED, "authentication required")
+			return
+		}
+		params := mux.Vars(r)
+		id := params["id"]
+		if id == "" {
+			problem.Error(w, r, http.StatusBadRequest, problem.CODE_BAD_REQUEST, "missing post id")
+			return
+		}
+		ownerID, err := postOwnerID(r.Context(), principal, id, models.PERMISSION_POSTS_DELETE_ANY)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusUnauthorized)
+			problem.WriteError(w, r, err)
 			return
 		}
-		if claims, ok := token.Claims.(*models.AppClaims); ok && token.Valid {
-			params := mux.Vars(r)
-			id := params["id"]
-			if id == "" {
-				http.Error(w, err.Error(), http.StatusBadRequest)
-				return
-			}
-			err = repositories.DeletePost(r.Context(), id, claims.UserID)
-			if err != nil {
-				http.Error(w, err.Error(), http.StatusInternalServerError)
-				return
-			}
-			w.WriteHeader(http.StatusOK)
-			w.Header().Set("Content-Type", "application/json")
-			json.NewEncoder(w).Encode(UpdatedPostResponse{
-				Message: "Post Deleted!",
-			})
-		} else {
-			http.Error(w, err.Error(), http.StatusUnauthorized)
+		err = repositories.DeletePost(r.Context(), id, ownerID)
+		if errors.Is(err, repositories.ErrNotFound) {
+			problem.Error(w, r, http.StatusNotFound, problem.CODE_NOT_FOUND, "post not found")
+			return
 		}
+		if err != nil {
+			problem.Internal(w, r, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(UpdatedPostResponse{
+			Message: "Post Deleted!",
+		})
 	}
 }
 
@@ -185,13 +210,13 @@ func ListPostHandler(s server.Server) http.HandlerFunc {
 		if pageStr != "" {
 			page, err = strconv.ParseUint(pageStr, 10, 64)
 			if err != nil {
-				http.Error(w, err.Error(), http.StatusBadRequest)
+				problem.Write(w, r, problem.New(http.StatusBadRequest, problem.CODE_VALIDATION_FAILED, "validation failed").WithErrors(map[string]string{"page": "must be a non-negative integer"}))
 				return
 			}
 		}
 		posts, err := repositories.ListPosts(r.Context(), page)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			problem.Internal(w, r, err)
 			return
 		}
 		w.Header().Set("Content-Type", "application/json")
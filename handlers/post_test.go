@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/th3khan/rest-web-sockets-with-go/auth"
+	"github.com/th3khan/rest-web-sockets-with-go/models"
+	"github.com/th3khan/rest-web-sockets-with-go/server"
+)
+
+func postRequest(method string, id string, userID string, body string) *http.Request {
+	r := httptest.NewRequest(method, "/posts/"+id, strings.NewReader(body))
+	r = mux.SetURLVars(r, map[string]string{"id": id})
+	return r.WithContext(auth.WithPrincipal(r.Context(), &auth.Principal{UserID: userID}))
+}
+
+func TestUpdatePostHandlerRequiresOwnership(t *testing.T) {
+	s := newTestServer(t)
+	tests := []struct {
+		name     string
+		id       string
+		userID   string
+		expected int
+	}{
+		{"owner", "post", "owner", http.StatusOK},
+		{"non-owner", "post", "other", http.StatusNotFound},
+		{"missing post", "missing", "owner", http.StatusNotFound},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			repository := newFakeRepository(t)
+			repository.posts["post"] = &models.Post{ID: "post", UserID: "owner", Title: "title"}
+			recorder := httptest.NewRecorder()
+			UpdatePostHandler(s)(recorder, postRequest(http.MethodPut, test.id, test.userID, `{"title":"changed","content":"body"}`))
+			if recorder.Code != test.expected {
+				t.Fatalf("expected %d, got %d", test.expected, recorder.Code)
+			}
+			if changed := repository.posts["post"].Title == "changed"; changed != (test.expected == http.StatusOK) {
+				t.Fatalf("unexpected post title %q", repository.posts["post"].Title)
+			}
+		})
+	}
+}
+
+func TestDeletePostHandlerRequiresOwnership(t *testing.T) {
+	s := newTestServer(t)
+	tests := []struct {
+		name     string
+		id       string
+		userID   string
+		expected int
+	}{
+		{"owner", "post", "owner", http.StatusOK},
+		{"non-owner", "post", "other", http.StatusNotFound},
+		{"missing post", "missing", "owner", http.StatusNotFound},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			repository := newFakeRepository(t)
+			repository.posts["post"] = &models.Post{ID: "post", UserID: "owner"}
+			recorder := httptest.NewRecorder()
+			DeletePostHandler(s)(recorder, postRequest(http.MethodDelete, test.id, test.userID, ""))
+			if recorder.Code != test.expected {
+				t.Fatalf("expected %d, got %d", test.expected, recorder.Code)
+			}
+			if _, exists := repository.posts["post"]; exists == (test.expected == http.StatusOK) {
+				t.Fatal("unexpected post state after delete")
+			}
+		})
+	}
+}
+
+func TestPostHandlersRequireVerifiedEmailFromRepository(t *testing.T) {
+	s, err := server.NewServer(context.Background(), &server.Config{
+		Port:                 ":0",
+		DataBaseUrl:          "test",
+		JWTSecret:            "test-secret",
+		Environment:          server.ENVIRONMENT_DEVELOPMENT,
+		RequireVerifiedEmail: REQUIRE_VERIFIED_POSTING,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	repository := newFakeRepository(t)
+	repository.users["owner"] = &models.User{ID: "owner", Email: "owner@example.com"}
+	repository.posts["post"] = &models.Post{ID: "post", UserID: "owner", Title: "title"}
+
+	request := func(method string, id string) *http.Request {
+		r := postRequest(method, id, "owner", `{"title":"changed","content":"body"}`)
+		return r.WithContext(auth.WithPrincipal(r.Context(), &auth.Principal{UserID: "owner", EmailVerified: true}))
+	}
+
+	recorder := httptest.NewRecorder()
+	InsertPostHandler(s)(recorder, request(http.MethodPost, ""))
+	if recorder.Code != http.StatusForbidden {
+		t.Fatalf("expected create to be forbidden, got %d", recorder.Code)
+	}
+	recorder = httptest.NewRecorder()
+	UpdatePostHandler(s)(recorder, request(http.MethodPut, "post"))
+	if recorder.Code != http.StatusForbidden {
+		t.Fatalf("expected update to be forbidden, got %d", recorder.Code)
+	}
+	if repository.posts["post"].Title != "title" {
+		t.Fatalf("expected post to be unchanged, got %q", repository.posts["post"].Title)
+	}
+
+	repository.users["owner"].EmailVerified = true
+	recorder = httptest.NewRecorder()
+	UpdatePostHandler(s)(recorder, request(http.MethodPut, "post"))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("expected verified update to succeed, got %d", recorder.Code)
+	}
+}
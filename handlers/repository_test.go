@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/th3khan/rest-web-sockets-with-go/models"
+	"github.com/th3khan/rest-web-sockets-with-go/repositories"
+	"github.com/th3khan/rest-web-sockets-with-go/server"
+)
+
+type fakeRepository struct {
+	repositories.Repository
+
+	mu            sync.Mutex
+	users         map[string]*models.User
+	totpSteps     map[string]int64
+	recoveryCodes map[string]map[string]bool
+	identities    map[string]*models.ExternalIdentity
+	lookups       int
+	pingErr       error
+	posts         map[string]*models.Post
+	roles         map[string][]string
+}
+
+func newFakeRepository(t *testing.T) *fakeRepository {
+	repository := &fakeRepository{
+		users:         map[string]*models.User{},
+		totpSteps:     map[string]int64{},
+		recoveryCodes: map[string]map[string]bool{},
+		identities:    map[string]*models.ExternalIdentity{},
+		posts:         map[string]*models.Post{},
+		roles:         map[string][]string{},
+	}
+	repositories.SetRepository(repository)
+	t.Cleanup(func() { repositories.SetRepository(nil) })
+	return repository
+}
+
+func newTestServer(t *testing.T) server.Server {
+	s, err := server.NewServer(context.Background(), &server.Config{
+		Port:        ":0",
+		DataBaseUrl: "test",
+		JWTSecret:   "test-secret",
+		Environment: server.ENVIRONMENT_DEVELOPMENT,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func (f *fakeRepository) GetUserById(ctx context.Context, id string) (*models.User, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.lookups++
+	return f.users[id], nil
+}
+
+func (f *fakeRepository) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, user := range f.users {
+		if user.Email == email {
+			return user, nil
+		}
+	}
+	return nil, nil
+}
+
+func (f *fakeRepository) UseTOTPStep(ctx context.Context, userID string, step int64) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if last, ok := f.totpSteps[userID]; ok && last >= step {
+		return false, nil
+	}
+	f.totpSteps[userID] = step
+	return true, nil
+}
+
+func (f *fakeRepository) UseRecoveryCode(ctx context.Context, userID string, hash string) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	codes := f.recoveryCodes[userID]
+	if used, ok := codes[hash]; !ok || used {
+		return false, nil
+	}
+	codes[hash] = true
+	return true, nil
+}
+
+func (f *fakeRepository) ReplaceRecoveryCodes(ctx context.Context, userID string, hashes []string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	codes := map[string]bool{}
+	for _, hash := range hashes {
+		codes[hash] = false
+	}
+	f.recoveryCodes[userID] = codes
+	return nil
+}
+
+func (f *fakeRepository) GetExternalIdentity(ctx context.Context, provider string, subject string) (*models.ExternalIdentity, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.identities[provider+" "+subject], nil
+}
+
+func (f *fakeRepository) InsertExternalIdentity(ctx context.Context, identity *models.ExternalIdentity) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	key := identity.Provider + " " + identity.Subject
+	if _, ok := f.identities[key]; ok {
+		return repositories.ErrDuplicate
+	}
+	f.identities[key] = identity
+	return nil
+}
+
+func (f *fakeRepository) Ping(ctx context.Context) error {
+	return f.pingErr
+}
+
+func (f *fakeRepository) SchemaVersion(ctx context.Context) (int, error) {
+	return 0, f.pingErr
+}
+
+func (f *fakeRepository) GetPostById(ctx context.Context, id string) (*models.Post, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.posts[id], nil
+}
+
+func (f *fakeRepository) UpdatePost(ctx context.Context, post *models.Post) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	existing, ok := f.posts[post.ID]
+	if !ok || existing.UserID != post.UserID {
+		return repositories.ErrNotFound
+	}
+	existing.Title = post.Title
+	existing.Content = post.Content
+	return nil
+}
+
+func (f *fakeRepository) DeletePost(ctx context.Context, id string, userID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	existing, ok := f.posts[id]
+	if !ok || existing.UserID != userID {
+		return repositories.ErrNotFound
+	}
+	delete(f.posts, id)
+	return nil
+}
+
+func (f *fakeRepository) SetUserRoles(ctx context.Context, userID string, roles []string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	seen := map[string]bool{}
+	for _, role := range roles {
+		if seen[role] {
+			return repositories.ErrDuplicate
+		}
+		seen[role] = true
+	}
+	f.roles[userID] = roles
+	return nil
+}
+
+func (f *fakeRepository) RevokeUserTokens(ctx context.Context, userID string) error {
+	return nil
+}
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/th3khan/rest-web-sockets-with-go/auth"
+	"github.com/th3khan/rest-web-sockets-with-go/problem"
+	"github.com/th3khan/rest-web-sockets-with-go/repositories"
+	"github.com/th3khan/rest-web-sockets-with-go/server"
+)
+
+const (
+	SESSION_USER_AGENT_MAX_LENGTH = 512
+)
+
+func truncate(value string, length int) string {
+	if len(value) <= length {
+		return value
+	}
+	return value[:length]
+}
+
+func ListSessionsHandler(s server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		principal, ok := auth.PrincipalFromContext(r.Context())
+		if !ok {
+			problem.Error(w, r, http.StatusUnauthorized, problem.CODE_UNAUTHORIZED, "authentication required")
+			return
+		}
+		sessions, err := repositories.ListSessions(r.Context(), principal.UserID)
+		if err != nil {
+			problem.Internal(w, r, err)
+			return
+		}
+		for _, session := range sessions {
+			session.Current = session.ID == principal.SessionID
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(sessions)
+	}
+}
+
+func RevokeSessionHandler(s server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		principal, ok := auth.PrincipalFromContext(r.Context())
+		if !ok {
+			problem.Error(w, r, http.StatusUnauthorized, problem.CODE_UNAUTHORIZED, "authentication required")
+			return
+		}
+		id := mux.Vars(r)["id"]
+		revoked, err := s.Revocations().RevokeSession(r.Context(), id, principal.UserID)
+		if err != nil {
+			problem.Internal(w, r, err)
+			return
+		}
+		if !revoked {
+			problem.Error(w, r, http.StatusNotFound, problem.CODE_NOT_FOUND, "session not found")
+			return
+		}
+		s.Hub().DisconnectSession(id)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
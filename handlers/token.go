@@ -0,0 +1,258 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/segmentio/ksuid"
+	"github.com/th3khan/rest-web-sockets-with-go/auth"
+	"github.com/th3khan/rest-web-sockets-with-go/models"
+	"github.com/th3khan/rest-web-sockets-with-go/problem"
+	"github.com/th3khan/rest-web-sockets-with-go/repositories"
+	"github.com/th3khan/rest-web-sockets-with-go/server"
+)
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func generateOpaqueToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+func issueAccessToken(ctx context.Context, s server.Server, userID string, sessionID string) (string, error) {
+	id, err := ksuid.NewRandom()
+	if err != nil {
+		return "", err
+	}
+	user, err := repositories.GetUserById(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	if user == nil {
+		return "", errors.New("user not found")
+	}
+	roles, err := repositories.GetUserRoles(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	permissions, err := repositories.GetRolePermissions(ctx, roles)
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
+	claims := models.AppClaims{
+		UserID:        userID,
+		Roles:         roles,
+		Permissions:   permissions,
+		EmailVerified: user.EmailVerified,
+		TokenVersion:  user.TokenVersion,
+		SessionID:     sessionID,
+		StandardClaims: jwt.StandardClaims{
+			Id:        id.String(),
+			Audience:  models.ACCESS_TOKEN_AUDIENCE,
+			IssuedAt:  now.Unix(),
+			ExpiresAt: now.Add(s.Config().AccessTokenTTL).Unix(),
+		},
+	}
+	return s.Keys().Sign(claims)
+}
+
+func issueTokens(r *http.Request, s server.Server, userID string, familyID string) (*LoginResponse, string, error) {
+	ctx := r.Context()
+	id, err := ksuid.NewRandom()
+	if err != nil {
+		return nil, "", err
+	}
+	expiresAt := time.Now().Add(s.Config().RefreshTokenTTL)
+	if familyID == "" {
+		familyID = id.String()
+		err = repositories.InsertSession(ctx, &models.Session{
+			ID:        familyID,
+			UserID:    userID,
+			UserAgent: truncate(r.UserAgent(), SESSION_USER_AGENT_MAX_LENGTH),
+			IP:        clientIP(r),
+			ExpiresAt: expiresAt,
+		})
+	} else {
+		err = repositories.RefreshSession(ctx, familyID, clientIP(r), expiresAt)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	accessToken, err := issueAccessToken(ctx, s, userID, familyID)
+	if err != nil {
+		return nil, "", err
+	}
+	refreshToken, err := generateOpaqueToken()
+	if err != nil {
+		return nil, "", err
+	}
+	err = repositories.InsertRefreshToken(ctx, &models.RefreshToken{
+		ID:        id.String(),
+		UserID:    userID,
+		FamilyID:  familyID,
+		TokenHash: hashToken(refreshToken),
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	return &LoginResponse{
+		Token:        accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(s.Config().AccessTokenTTL.Seconds()),
+	}, id.String(), nil
+}
+
+func rejectReusedRefreshToken(w http.ResponseWriter, r *http.Request, s server.Server, token *models.RefreshToken) {
+	s.Logger().Warn("refresh token reuse detected", "user_id", token.UserID, "family_id", token.FamilyID)
+	if err := repositories.RevokeRefreshTokenFamily(r.Context(), token.FamilyID); err != nil {
+		problem.Internal(w, r, err)
+		return
+	}
+	problem.Error(w, r, http.StatusUnauthorized, problem.CODE_INVALID_TOKEN, "invalid refresh token")
+}
+
+func RefreshTokenHandler(s server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var request RefreshTokenRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			problem.MalformedBody(w, r, err)
+			return
+		}
+		current, err := repositories.GetRefreshTokenByHash(r.Context(), hashToken(request.RefreshToken))
+		if err != nil {
+			problem.Internal(w, r, err)
+			return
+		}
+		if current == nil {
+			problem.Error(w, r, http.StatusUnauthorized, problem.CODE_INVALID_TOKEN, "invalid refresh token")
+			return
+		}
+		if current.RevokedAt != nil {
+			rejectReusedRefreshToken(w, r, s, current)
+			return
+		}
+		if time.Now().After(current.ExpiresAt) {
+			problem.Error(w, r, http.StatusUnauthorized, problem.CODE_INVALID_TOKEN, "refresh token expired")
+			return
+		}
+
+		response, newID, err := issueTokens(r, s, current.UserID, current.FamilyID)
+		if err != nil {
+			problem.Internal(w, r, err)
+			return
+		}
+		revoked, err := repositories.RevokeRefreshToken(r.Context(), current.ID, newID)
+		if err != nil {
+			problem.Internal(w, r, err)
+			return
+		}
+		if !revoked {
+			rejectReusedRefreshToken(w, r, s, current)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+type LogoutResponse struct {
+	Message string `json:"message"`
+}
+
+func LogoutHandler(s server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		principal, ok := auth.PrincipalFromContext(r.Context())
+		if !ok {
+			problem.Error(w, r, http.StatusUnauthorized, problem.CODE_UNAUTHORIZED, "authentication required")
+			return
+		}
+
+		var request RefreshTokenRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil && err != io.EOF {
+			problem.MalformedBody(w, r, err)
+			return
+		}
+		if request.RefreshToken != "" {
+			refreshToken, err := repositories.GetRefreshTokenByHash(r.Context(), hashToken(request.RefreshToken))
+			if err != nil {
+				problem.Internal(w, r, err)
+				return
+			}
+			if refreshToken != nil && refreshToken.UserID == principal.UserID {
+				if err := repositories.RevokeRefreshTokenFamily(r.Context(), refreshToken.FamilyID); err != nil {
+					problem.Internal(w, r, err)
+					return
+				}
+			}
+		}
+
+		if err := s.Revocations().RevokeToken(r.Context(), principal.TokenID, principal.UserID, principal.TokenExpiresAt); err != nil {
+			problem.Internal(w, r, err)
+			return
+		}
+		s.Hub().DisconnectToken(principal.TokenID)
+		if principal.SessionID != "" {
+			if _, err := s.Revocations().RevokeSession(r.Context(), principal.SessionID, principal.UserID); err != nil {
+				problem.Internal(w, r, err)
+				return
+			}
+			s.Hub().DisconnectSession(principal.SessionID)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(LogoutResponse{
+			Message: "Logged out",
+		})
+	}
+}
+
+func LogoutAllHandler(s server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		principal, ok := auth.PrincipalFromContext(r.Context())
+		if !ok {
+			problem.Error(w, r, http.StatusUnauthorized, problem.CODE_UNAUTHORIZED, "authentication required")
+			return
+		}
+
+		if err := s.Revocations().RevokeUser(r.Context(), principal.UserID); err != nil {
+			problem.Internal(w, r, err)
+			return
+		}
+		s.Hub().DisconnectUser(principal.UserID)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(LogoutResponse{
+			Message: "Logged out from all sessions",
+		})
+	}
+}
+
+func JWKSHandler(s server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "public, max-age=300")
+		json.NewEncoder(w).Encode(s.Keys().JWKS())
+	}
+}
@@ -2,20 +2,16 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
-	"strings"
-	"time"
 
-	"github.com/golang-jwt/jwt"
 	"github.com/segmentio/ksuid"
+	"github.com/th3khan/rest-web-sockets-with-go/auth"
 	"github.com/th3khan/rest-web-sockets-with-go/models"
+	"github.com/th3khan/rest-web-sockets-with-go/problem"
 	"github.com/th3khan/rest-web-sockets-with-go/repositories"
 	"github.com/th3khan/rest-web-sockets-with-go/server"
-	"golang.org/x/crypto/bcrypt"
-)
-
-const (
-	HASH_COST = 8
+	"github.com/th3khan/rest-web-sockets-with-go/validation"
 )
 
 type SignUpLoginRequest struct {
@@ -29,7 +25,9 @@ type SignUpResponse struct {
 }
 
 type LoginResponse struct {
-	Token string `json:"token"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"`
 }
 
 func SignUpHandler(s server.Server) http.HandlerFunc {
@@ -37,32 +35,52 @@ func SignUpHandler(s server.Server) http.HandlerFunc {
 		var request SignUpLoginRequest
 		err := json.NewDecoder(r.Body).Decode(&request)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			problem.MalformedBody(w, r, err)
+			return
+		}
+		request.Email = validation.NormalizeEmail(request.Email)
+		errs := validation.Errors{}
+		validation.ValidateEmail(errs, "email", request.Email)
+		validatePassword(s, errs, "password", request.Password)
+		if !errs.Empty() {
+			problem.Validation(w, r, http.StatusUnprocessableEntity, errs)
 			return
 		}
 
-		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(request.Password), HASH_COST)
+		hashedPassword, err := s.Passwords().Hash(request.Password)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			problem.Internal(w, r, err)
 			return
 		}
 
 		id, err := ksuid.NewRandom()
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			problem.Internal(w, r, err)
 			return
 		}
 		user := models.User{
 			ID:       id.String(),
 			Email:    request.Email,
-			Password: string(hashedPassword),
+			Password: hashedPassword,
 		}
 
 		err = repositories.InsertUser(r.Context(), &user)
+		if errors.Is(err, repositories.ErrDuplicate) {
+			problem.Write(w, r, problem.New(http.StatusConflict, problem.CODE_CONFLICT, "email is already registered").WithErrors(validation.Errors{"email": "is already registered"}))
+			return
+		}
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			problem.Internal(w, r, err)
 			return
 		}
+		err = repositories.SetUserRoles(r.Context(), user.ID, []string{models.ROLE_USER})
+		if err != nil {
+			problem.Internal(w, r, err)
+			return
+		}
+		if err := sendVerificationEmail(r.Context(), s, &user); err != nil {
+			s.Logger().Error("could not send verification email", "user_id", user.ID, "error", err)
+		}
 
 		response := SignUpResponse{
 			ID:    user.ID,
@@ -78,65 +96,64 @@ func LoginHanlder(s server.Server) http.HandlerFunc {
 		var request SignUpLoginRequest
 		err := json.NewDecoder(r.Body).Decode(&request)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			problem.MalformedBody(w, r, err)
+			return
+		}
+		request.Email = validation.NormalizeEmail(request.Email)
+		errs := validation.Errors{}
+		validation.Required(errs, "email", request.Email)
+		validation.Required(errs, "password", request.Password)
+		if !errs.Empty() {
+			problem.Validation(w, r, http.StatusUnprocessableEntity, errs)
+			return
+		}
+		if !checkLoginAllowed(w, r, s, request.Email) {
 			return
 		}
 		user, err := repositories.GetUserByEmail(r.Context(), request.Email)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			problem.Internal(w, r, err)
 			return
 		}
 		if user == nil {
-			http.Error(w, "invalid credentials", http.StatusUnauthorized)
+			problem.Error(w, r, http.StatusUnauthorized, problem.CODE_INVALID_CREDENTIALS, "invalid credentials")
 			return
 		}
 
-		if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(request.Password)); err != nil {
-			http.Error(w, "invalid credentials", http.StatusUnauthorized)
+		valid, err := s.Passwords().Verify(user.Password, request.Password)
+		if err != nil || !valid {
+			problem.Error(w, r, http.StatusUnauthorized, problem.CODE_INVALID_CREDENTIALS, "invalid credentials")
 			return
 		}
-
-		claims := models.AppClaims{
-			UserID: user.ID,
-			StandardClaims: jwt.StandardClaims{
-				ExpiresAt: time.Now().Add(time.Hour * 24).Unix(),
-			},
+		recordLoginSuccess(r, s, request.Email)
+		if s.Passwords().NeedsRehash(user.Password) {
+			rehashPassword(r.Context(), s, user.ID, request.Password)
 		}
-
-		token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-		tokenString, err := token.SignedString([]byte(s.Config().JWTSecret))
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+		if s.Config().RequireVerifiedEmail == REQUIRE_VERIFIED_LOGIN && !user.EmailVerified {
+			problem.Error(w, r, http.StatusForbidden, problem.CODE_EMAIL_NOT_VERIFIED, "email address not verified")
 			return
 		}
-
-		response := LoginResponse{
-			Token: tokenString,
-		}
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(response)
+		completeLogin(w, r, s, user.ID)
 	}
 }
 
 func MeHandler(s server.Server) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		tokenString := strings.TrimSpace(r.Header.Get("Authorization"))
-		token, err := jwt.ParseWithClaims(tokenString, &models.AppClaims{}, func(token *jwt.Token) (interface{}, error) {
-			return []byte(s.Config().JWTSecret), nil
-		})
+		principal, ok := auth.PrincipalFromContext(r.Context())
+		if !ok {
+			problem.Error(w, r, http.StatusUnauthorized, problem.CODE_UNAUTHORIZED, "authentication required")
+			return
+		}
+		user, err := repositories.GetUserById(r.Context(), principal.UserID)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusUnauthorized)
+			problem.Internal(w, r, err)
 			return
 		}
-		if claims, ok := token.Claims.(*models.AppClaims); ok && token.Valid {
-			user, err := repositories.GetUserById(r.Context(), claims.UserID)
-			if err != nil {
-				http.Error(w, err.Error(), http.StatusInternalServerError)
-			}
-			w.Header().Set("Content-Type", "application/json")
-			json.NewEncoder(w).Encode(user)
-		} else {
-			http.Error(w, err.Error(), http.StatusUnauthorized)
+		if user == nil {
+			problem.Error(w, r, http.StatusNotFound, problem.CODE_NOT_FOUND, "user not found")
+			return
 		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(user)
 	}
 }
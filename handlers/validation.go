@@ -0,0 +1,14 @@
+package handlers
+
+import (
+	"strings"
+
+	"github.com/th3khan/rest-web-sockets-with-go/server"
+	"github.com/th3khan/rest-web-sockets-with-go/validation"
+)
+
+func validatePassword(s server.Server, errs validation.Errors, field string, password string) {
+	if problems := s.Passwords().Validate(password); len(problems) > 0 {
+		errs.Add(field, strings.Join(problems, ", "))
+	}
+}
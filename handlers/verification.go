@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/segmentio/ksuid"
+	"github.com/th3khan/rest-web-sockets-with-go/auth"
+	"github.com/th3khan/rest-web-sockets-with-go/mailer"
+	"github.com/th3khan/rest-web-sockets-with-go/models"
+	"github.com/th3khan/rest-web-sockets-with-go/problem"
+	"github.com/th3khan/rest-web-sockets-with-go/repositories"
+	"github.com/th3khan/rest-web-sockets-with-go/server"
+	"github.com/th3khan/rest-web-sockets-with-go/validation"
+)
+
+const (
+	REQUIRE_VERIFIED_LOGIN   = "login"
+	REQUIRE_VERIFIED_POSTING = "posting"
+)
+
+type VerifyEmailRequest struct {
+	Token string `json:"token"`
+}
+
+type ResendVerificationEmailRequest struct {
+	Email string `json:"email"`
+}
+
+type VerifyEmailResponse struct {
+	Message string `json:"message"`
+}
+
+func requireVerifiedPosting(ctx context.Context, s server.Server, principal *auth.Principal) error {
+	if s.Config().RequireVerifiedEmail != REQUIRE_VERIFIED_POSTING {
+		return nil
+	}
+	user, err := repositories.GetUserById(ctx, principal.UserID)
+	if err != nil {
+		return err
+	}
+	if user == nil || !user.EmailVerified {
+		return problem.New(http.StatusForbidden, problem.CODE_EMAIL_NOT_VERIFIED, "email address not verified")
+	}
+	return nil
+}
+
+func sendVerificationEmail(ctx context.Context, s server.Server, user *models.User) error {
+	token, err := generateOpaqueToken()
+	if err != nil {
+		return err
+	}
+	id, err := ksuid.NewRandom()
+	if err != nil {
+		return err
+	}
+	err = repositories.InsertEmailVerificationToken(ctx, &models.EmailVerificationToken{
+		ID:        id.String(),
+		UserID:    user.ID,
+		Email:     user.Email,
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().Add(s.Config().EmailVerificationTTL),
+	})
+	if err != nil {
+		return err
+	}
+	link := fmt.Sprintf("%s/verify-email?token=%s", s.Config().AppBaseURL, url.QueryEscape(token))
+	return s.Mailer().Send(ctx, &mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body:    fmt.Sprintf("Confirm your email address by opening the following link:\n\n%s\n\nOr send this token to POST /v1/verify-email:\n\n%s\n", link, token),
+	})
+}
+
+func VerifyEmailHandler(s server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var request VerifyEmailRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			problem.MalformedBody(w, r, err)
+			return
+		}
+		token, err := repositories.GetEmailVerificationTokenByHash(r.Context(), hashToken(request.Token))
+		if err != nil {
+			problem.Internal(w, r, err)
+			return
+		}
+		if token == nil || token.UsedAt != nil || time.Now().After(token.ExpiresAt) {
+			problem.Error(w, r, http.StatusBadRequest, problem.CODE_INVALID_TOKEN, "invalid verification token")
+			return
+		}
+		user, err := repositories.GetUserById(r.Context(), token.UserID)
+		if err != nil {
+			problem.Internal(w, r, err)
+			return
+		}
+		if user == nil || user.Email != token.Email {
+			problem.Error(w, r, http.StatusBadRequest, problem.CODE_INVALID_TOKEN, "invalid verification token")
+			return
+		}
+		used, err := repositories.MarkEmailVerificationTokenUsed(r.Context(), token.ID)
+		if err != nil {
+			problem.Internal(w, r, err)
+			return
+		}
+		if !used {
+			problem.Error(w, r, http.StatusBadRequest, problem.CODE_INVALID_TOKEN, "invalid verification token")
+			return
+		}
+		if err := repositories.MarkEmailVerified(r.Context(), user.ID); err != nil {
+			problem.Internal(w, r, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(VerifyEmailResponse{
+			Message: "Email verified",
+		})
+	}
+}
+
+func ResendVerificationEmailHandler(s server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var request ResendVerificationEmailRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			problem.MalformedBody(w, r, err)
+			return
+		}
+		request.Email = validation.NormalizeEmail(request.Email)
+		errs := validation.Errors{}
+		validation.ValidateEmail(errs, "email", request.Email)
+		if !errs.Empty() {
+			problem.Validation(w, r, http.StatusUnprocessableEntity, errs)
+			return
+		}
+
+		ctx := context.WithoutCancel(r.Context())
+		go func() {
+			user, err := repositories.GetUserByEmail(ctx, request.Email)
+			if err != nil {
+				s.Logger().Error("could not look up user for email verification", "error", err)
+				return
+			}
+			if user == nil || user.EmailVerified {
+				return
+			}
+			if err := sendVerificationEmail(ctx, s, user); err != nil {
+				s.Logger().Error("could not send verification email", "user_id", user.ID, "error", err)
+			}
+		}()
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(VerifyEmailResponse{
+			Message: "If the email is registered and not yet verified, a verification link has been sent",
+		})
+	}
+}
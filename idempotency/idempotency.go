@@ -0,0 +1,126 @@
+package idempotency
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/th3khan/rest-web-sockets-with-go/models"
+)
+
+const (
+	DRIVER_MEMORY   = "memory"
+	DRIVER_DATABASE = "database"
+
+	HEADER         = "Idempotency-Key"
+	MAX_KEY_LENGTH = 255
+	MAX_BODY_SIZE  = 1 << 20
+
+	DEFAULT_TTL   = 24 * time.Hour
+	DEFAULT_LEASE = time.Minute
+)
+
+var (
+	ErrInProgress = errors.New("a request with this idempotency key is still in progress")
+	ErrMismatch   = errors.New("idempotency key was already used for a different request")
+)
+
+type Store interface {
+	Reserve(ctx context.Context, record *models.IdempotencyRecord) (bool, error)
+	Get(ctx context.Context, userID string, key string) (*models.IdempotencyRecord, error)
+	Complete(ctx context.Context, record *models.IdempotencyRecord) error
+	Delete(ctx context.Context, userID string, key string) error
+}
+
+type Config struct {
+	Driver string
+	TTL    time.Duration
+	Lease  time.Duration
+}
+
+type Cache struct {
+	store Store
+	ttl   time.Duration
+	lease time.Duration
+}
+
+func New(config *Config) (*Cache, error) {
+	var store Store
+	switch config.Driver {
+	case "", DRIVER_MEMORY:
+		store = NewMemoryStore()
+	case DRIVER_DATABASE:
+		store = NewRepositoryStore()
+	default:
+		return nil, fmt.Errorf("unknown idempotency driver %s", config.Driver)
+	}
+	return NewCache(store, config.TTL, config.Lease), nil
+}
+
+func NewCache(store Store, ttl time.Duration, lease time.Duration) *Cache {
+	if ttl <= 0 {
+		ttl = DEFAULT_TTL
+	}
+	if lease <= 0 {
+		lease = DEFAULT_LEASE
+	}
+	return &Cache{
+		store: store,
+		ttl:   ttl,
+		lease: lease,
+	}
+}
+
+func Fingerprint(method string, path string, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(method + " " + path + "\n"))
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func (c *Cache) Begin(ctx context.Context, userID string, key string, fingerprint string) (*models.IdempotencyRecord, error) {
+	reserved, err := c.store.Reserve(ctx, &models.IdempotencyRecord{
+		UserID:      userID,
+		Key:         key,
+		Fingerprint: fingerprint,
+		ExpiresAt:   time.Now().Add(c.lease),
+	})
+	if err != nil || reserved {
+		return nil, err
+	}
+	record, err := c.store.Get(ctx, userID, key)
+	if err != nil {
+		return nil, err
+	}
+	if record == nil {
+		return nil, ErrInProgress
+	}
+	if record.Fingerprint != fingerprint {
+		return nil, ErrMismatch
+	}
+	if record.CompletedAt == nil {
+		return nil, ErrInProgress
+	}
+	return record, nil
+}
+
+func (c *Cache) Complete(ctx context.Context, userID string, key string, status int, header http.Header, body []byte) error {
+	now := time.Now()
+	return c.store.Complete(ctx, &models.IdempotencyRecord{
+		UserID:      userID,
+		Key:         key,
+		StatusCode:  status,
+		Header:      header,
+		Body:        body,
+		CompletedAt: &now,
+		ExpiresAt:   now.Add(c.ttl),
+	})
+}
+
+func (c *Cache) Release(ctx context.Context, userID string, key string) error {
+	return c.store.Delete(ctx, userID, key)
+}
@@ -0,0 +1,38 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestAbandonedReservationExpiresAfterLease(t *testing.T) {
+	cache := NewCache(NewMemoryStore(), time.Hour, 50*time.Millisecond)
+	ctx := context.Background()
+	if _, err := cache.Begin(ctx, "user", "key", "fingerprint"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cache.Begin(ctx, "user", "key", "fingerprint"); !errors.Is(err, ErrInProgress) {
+		t.Fatalf("expected the reservation to be in progress, got %v", err)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	record, err := cache.Begin(ctx, "user", "key", "fingerprint")
+	if err != nil || record != nil {
+		t.Fatalf("expected the retry to reclaim the abandoned reservation, got %v %v", record, err)
+	}
+	if err = cache.Complete(ctx, "user", "key", http.StatusCreated, http.Header{}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	record, err = cache.Begin(ctx, "user", "key", "fingerprint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record == nil || record.StatusCode != http.StatusCreated {
+		t.Fatalf("expected the completed response to outlive the lease, got %v", record)
+	}
+}
@@ -0,0 +1,90 @@
+package idempotency
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/th3khan/rest-web-sockets-with-go/models"
+)
+
+const (
+	MEMORY_PRUNE_INTERVAL = time.Minute
+)
+
+type MemoryStore struct {
+	mutex   *sync.Mutex
+	records map[string]models.IdempotencyRecord
+	pruned  time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		mutex:   &sync.Mutex{},
+		records: make(map[string]models.IdempotencyRecord),
+		pruned:  time.Now(),
+	}
+}
+
+func memoryKey(userID string, key string) string {
+	return userID + "|" + key
+}
+
+func (s *MemoryStore) prune(now time.Time) {
+	if now.Sub(s.pruned) < MEMORY_PRUNE_INTERVAL {
+		return
+	}
+	s.pruned = now
+	for key, record := range s.records {
+		if now.After(record.ExpiresAt) {
+			delete(s.records, key)
+		}
+	}
+}
+
+func (s *MemoryStore) Reserve(ctx context.Context, record *models.IdempotencyRecord) (bool, error) {
+	now := time.Now()
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.prune(now)
+	key := memoryKey(record.UserID, record.Key)
+	if existing, ok := s.records[key]; ok && now.Before(existing.ExpiresAt) {
+		return false, nil
+	}
+	s.records[key] = *record
+	return true, nil
+}
+
+func (s *MemoryStore) Get(ctx context.Context, userID string, key string) (*models.IdempotencyRecord, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	record, ok := s.records[memoryKey(userID, key)]
+	if !ok || time.Now().After(record.ExpiresAt) {
+		return nil, nil
+	}
+	return &record, nil
+}
+
+func (s *MemoryStore) Complete(ctx context.Context, record *models.IdempotencyRecord) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	key := memoryKey(record.UserID, record.Key)
+	existing, ok := s.records[key]
+	if !ok {
+		return nil
+	}
+	existing.StatusCode = record.StatusCode
+	existing.Header = record.Header
+	existing.Body = record.Body
+	existing.CompletedAt = record.CompletedAt
+	existing.ExpiresAt = record.ExpiresAt
+	s.records[key] = existing
+	return nil
+}
+
+func (s *MemoryStore) Delete(ctx context.Context, userID string, key string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.records, memoryKey(userID, key))
+	return nil
+}
@@ -0,0 +1,30 @@
+package idempotency
+
+import (
+	"context"
+
+	"github.com/th3khan/rest-web-sockets-with-go/models"
+	"github.com/th3khan/rest-web-sockets-with-go/repositories"
+)
+
+type RepositoryStore struct{}
+
+func NewRepositoryStore() *RepositoryStore {
+	return &RepositoryStore{}
+}
+
+func (s *RepositoryStore) Reserve(ctx context.Context, record *models.IdempotencyRecord) (bool, error) {
+	return repositories.ReserveIdempotencyKey(ctx, record)
+}
+
+func (s *RepositoryStore) Get(ctx context.Context, userID string, key string) (*models.IdempotencyRecord, error) {
+	return repositories.GetIdempotencyRecord(ctx, userID, key)
+}
+
+func (s *RepositoryStore) Complete(ctx context.Context, record *models.IdempotencyRecord) error {
+	return repositories.CompleteIdempotencyRecord(ctx, record)
+}
+
+func (s *RepositoryStore) Delete(ctx context.Context, userID string, key string) error {
+	return repositories.DeleteIdempotencyRecord(ctx, userID, key)
+}
@@ -0,0 +1,478 @@
+package keys
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+const (
+	ALGORITHM_HS256 = "HS256"
+	ALGORITHM_RS256 = "RS256"
+	ALGORITHM_EDDSA = "EdDSA"
+
+	RSA_KEY_BITS = 2048
+
+	KEY_RELOAD_INTERVAL = 30 * time.Second
+	KEY_FILE_EXTENSION  = ".pem"
+
+	ROTATION_LOCK_FILE    = ".rotation.lock"
+	ROTATION_LOCK_TIMEOUT = time.Minute
+	ROTATION_LOCK_RETRY   = 100 * time.Millisecond
+)
+
+type Config struct {
+	Algorithm        string
+	Secret           string
+	PrivateKeyFile   string
+	KeyDir           string
+	RotationInterval time.Duration
+	RetentionPeriod  time.Duration
+	AllowEphemeral   bool
+}
+
+type Key struct {
+	ID        string
+	Algorithm string
+	signer    interface{}
+	verifier  interface{}
+	CreatedAt time.Time
+	RetiredAt *time.Time
+}
+
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+}
+
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+type Manager struct {
+	mutex    *sync.RWMutex
+	config   *Config
+	keys     []*Key
+	reloaded time.Time
+}
+
+func NewManager(config *Config) (*Manager, error) {
+	if config.Algorithm == "" {
+		config.Algorithm = ALGORITHM_HS256
+	}
+	manager := &Manager{
+		mutex:  &sync.RWMutex{},
+		config: config,
+	}
+
+	var key *Key
+	var err error
+	switch {
+	case config.Algorithm == ALGORITHM_HS256:
+		if config.Secret == "" {
+			return nil, errors.New("Secret Key is Required")
+		}
+		key = &Key{
+			ID:        "default",
+			Algorithm: ALGORITHM_HS256,
+			signer:    []byte(config.Secret),
+			verifier:  []byte(config.Secret),
+			CreatedAt: time.Now(),
+		}
+	case config.PrivateKeyFile != "":
+		if config.RotationInterval > 0 {
+			return nil, errors.New("key rotation requires a shared key directory, set JWT_KEY_DIR instead of JWT_PRIVATE_KEY_FILE")
+		}
+		key, err = loadKey(config.Algorithm, config.PrivateKeyFile)
+	case config.KeyDir != "":
+		if err = manager.reload(); err != nil {
+			return nil, err
+		}
+		if len(manager.keys) == 0 {
+			if err = manager.Rotate(); err != nil {
+				return nil, err
+			}
+		}
+		return manager, nil
+	case config.AllowEphemeral:
+		key, err = generateKey(config.Algorithm)
+	default:
+		return nil, fmt.Errorf("%s requires JWT_PRIVATE_KEY_FILE or JWT_KEY_DIR", config.Algorithm)
+	}
+	if err != nil {
+		return nil, err
+	}
+	manager.keys = []*Key{key}
+	return manager, nil
+}
+
+func loadKey(algorithm string, path string) (*Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found in %s", path)
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		if rsaKey, rsaErr := x509.ParsePKCS1PrivateKey(block.Bytes); rsaErr == nil {
+			parsed = rsaKey
+		} else {
+			return nil, err
+		}
+	}
+	signer, ok := parsed.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key in %s", path)
+	}
+	return newKey(algorithm, signer)
+}
+
+func generateSigner(algorithm string) (crypto.Signer, error) {
+	switch algorithm {
+	case ALGORITHM_RS256:
+		return rsa.GenerateKey(rand.Reader, RSA_KEY_BITS)
+	case ALGORITHM_EDDSA:
+		_, private, err := ed25519.GenerateKey(rand.Reader)
+		return private, err
+	}
+	return nil, fmt.Errorf("unsupported signing algorithm %s", algorithm)
+}
+
+func generateKey(algorithm string) (*Key, error) {
+	signer, err := generateSigner(algorithm)
+	if err != nil {
+		return nil, err
+	}
+	return newKey(algorithm, signer)
+}
+
+func newKey(algorithm string, signer crypto.Signer) (*Key, error) {
+	switch signer.(type) {
+	case *rsa.PrivateKey:
+		if algorithm != ALGORITHM_RS256 {
+			return nil, fmt.Errorf("RSA key cannot be used with %s", algorithm)
+		}
+	case ed25519.PrivateKey:
+		if algorithm != ALGORITHM_EDDSA {
+			return nil, fmt.Errorf("Ed25519 key cannot be used with %s", algorithm)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported key type for %s", algorithm)
+	}
+	der, err := x509.MarshalPKIXPublicKey(signer.Public())
+	if err != nil {
+		return nil, err
+	}
+	thumbprint := sha256.Sum256(der)
+	return &Key{
+		ID:        base64.RawURLEncoding.EncodeToString(thumbprint[:12]),
+		Algorithm: algorithm,
+		signer:    signer,
+		verifier:  signer.Public(),
+		CreatedAt: time.Now(),
+	}, nil
+}
+
+func (m *Manager) current() *Key {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	return m.keys[len(m.keys)-1]
+}
+
+func (m *Manager) Sign(claims jwt.Claims) (string, error) {
+	key := m.current()
+	token := jwt.NewWithClaims(jwt.GetSigningMethod(key.Algorithm), claims)
+	if key.Algorithm != ALGORITHM_HS256 {
+		token.Header["kid"] = key.ID
+	}
+	return token.SignedString(key.signer)
+}
+
+func (m *Manager) retained(key *Key, now time.Time) bool {
+	return key.RetiredAt == nil || now.Sub(*key.RetiredAt) < m.config.RetentionPeriod
+}
+
+func (m *Manager) Keyfunc(token *jwt.Token) (interface{}, error) {
+	verifier, err := m.lookup(token)
+	if err != nil && m.config.KeyDir != "" && m.reloadDue(time.Second) {
+		if reloadErr := m.reload(); reloadErr != nil {
+			return nil, reloadErr
+		}
+		return m.lookup(token)
+	}
+	return verifier, err
+}
+
+func (m *Manager) lookup(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	now := time.Now()
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	for _, key := range m.keys {
+		if !m.retained(key, now) {
+			continue
+		}
+		if (kid == "" && key.Algorithm == ALGORITHM_HS256) || key.ID == kid {
+			if token.Method.Alg() != key.Algorithm {
+				return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+			}
+			return key.verifier, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+func (m *Manager) Rotate() error {
+	if m.config.Algorithm == ALGORITHM_HS256 {
+		return errors.New("HS256 keys cannot be rotated")
+	}
+	if m.config.KeyDir != "" {
+		return m.rotateDir()
+	}
+	if !m.config.AllowEphemeral {
+		return errors.New("key rotation requires a shared key directory")
+	}
+	key, err := generateKey(m.config.Algorithm)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	keys := make([]*Key, 0, len(m.keys)+1)
+	for _, existing := range m.keys {
+		if existing.RetiredAt == nil {
+			existing.RetiredAt = &now
+		}
+		if m.retained(existing, now) {
+			keys = append(keys, existing)
+		}
+	}
+	m.keys = append(keys, key)
+	return nil
+}
+
+func (m *Manager) lockRotation() (func(), error) {
+	path := filepath.Join(m.config.KeyDir, ROTATION_LOCK_FILE)
+	for {
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if err == nil {
+			file.Close()
+			return func() { os.Remove(path) }, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, err
+		}
+		if info, err := os.Stat(path); err == nil && time.Since(info.ModTime()) > ROTATION_LOCK_TIMEOUT {
+			os.Remove(path)
+			continue
+		}
+		time.Sleep(ROTATION_LOCK_RETRY)
+	}
+}
+
+func (m *Manager) rotateDir() error {
+	m.mutex.RLock()
+	previous := ""
+	if len(m.keys) > 0 {
+		previous = m.keys[len(m.keys)-1].ID
+	}
+	m.mutex.RUnlock()
+
+	unlock, err := m.lockRotation()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if err = m.reload(); err != nil {
+		return err
+	}
+	m.mutex.RLock()
+	rotated := len(m.keys) > 0 && m.keys[len(m.keys)-1].ID != previous
+	m.mutex.RUnlock()
+	if rotated {
+		return nil
+	}
+
+	signer, err := generateSigner(m.config.Algorithm)
+	if err != nil {
+		return err
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(signer)
+	if err != nil {
+		return err
+	}
+	name := strconv.FormatInt(time.Now().UnixNano(), 10) + KEY_FILE_EXTENSION
+	temp, err := os.CreateTemp(m.config.KeyDir, ".key-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temp.Name())
+	if err = pem.Encode(temp, &pem.Block{Type: "PRIVATE KEY", Bytes: der}); err != nil {
+		temp.Close()
+		return err
+	}
+	if err = temp.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(temp.Name(), filepath.Join(m.config.KeyDir, name)); err != nil {
+		return err
+	}
+	return m.reload()
+}
+
+func (m *Manager) reloadDue(interval time.Duration) bool {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	return time.Since(m.reloaded) >= interval
+}
+
+func (m *Manager) reload() error {
+	entries, err := os.ReadDir(m.config.KeyDir)
+	if err != nil {
+		return err
+	}
+	var keys []*Key
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, KEY_FILE_EXTENSION) {
+			continue
+		}
+		created, err := strconv.ParseInt(strings.TrimSuffix(name, KEY_FILE_EXTENSION), 10, 64)
+		if err != nil {
+			continue
+		}
+		key, err := loadKey(m.config.Algorithm, filepath.Join(m.config.KeyDir, name))
+		if err != nil {
+			return err
+		}
+		key.CreatedAt = time.Unix(0, created)
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+	})
+	now := time.Now()
+	retained := make([]*Key, 0, len(keys))
+	for i, key := range keys {
+		if i < len(keys)-1 {
+			retiredAt := keys[i+1].CreatedAt
+			key.RetiredAt = &retiredAt
+		}
+		if !m.retained(key, now) {
+			os.Remove(filepath.Join(m.config.KeyDir, strconv.FormatInt(key.CreatedAt.UnixNano(), 10)+KEY_FILE_EXTENSION))
+			continue
+		}
+		retained = append(retained, key)
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.reloaded = now
+	if len(retained) > 0 {
+		m.keys = retained
+	}
+	return nil
+}
+
+func (m *Manager) rotationDue() bool {
+	if m.config.RotationInterval <= 0 {
+		return false
+	}
+	return time.Since(m.current().CreatedAt) >= m.config.RotationInterval
+}
+
+func (m *Manager) Run(ctx context.Context) error {
+	if m.config.Algorithm == ALGORITHM_HS256 {
+		return nil
+	}
+	interval := m.config.RotationInterval
+	if m.config.KeyDir != "" {
+		interval = KEY_RELOAD_INTERVAL
+	}
+	if interval <= 0 {
+		return nil
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if m.config.KeyDir != "" {
+				if err := m.reload(); err != nil {
+					return err
+				}
+			}
+			if m.rotationDue() {
+				if err := m.Rotate(); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+func (m *Manager) JWKS() JWKSet {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	set := JWKSet{
+		Keys: make([]JWK, 0, len(m.keys)),
+	}
+	now := time.Now()
+	for _, key := range m.keys {
+		if !m.retained(key, now) {
+			continue
+		}
+		switch public := key.verifier.(type) {
+		case *rsa.PublicKey:
+			set.Keys = append(set.Keys, JWK{
+				KeyType:   "RSA",
+				KeyID:     key.ID,
+				Use:       "sig",
+				Algorithm: key.Algorithm,
+				N:         base64.RawURLEncoding.EncodeToString(public.N.Bytes()),
+				E:         base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes()),
+			})
+		case ed25519.PublicKey:
+			set.Keys = append(set.Keys, JWK{
+				KeyType:   "OKP",
+				KeyID:     key.ID,
+				Use:       "sig",
+				Algorithm: key.Algorithm,
+				Curve:     "Ed25519",
+				X:         base64.RawURLEncoding.EncodeToString(public),
+			})
+		}
+	}
+	return set
+}
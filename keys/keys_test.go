@@ -0,0 +1,115 @@
+package keys
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+func TestKeyDirSharesKeysBetweenManagers(t *testing.T) {
+	dir := t.TempDir()
+	config := func() *Config {
+		return &Config{Algorithm: ALGORITHM_EDDSA, KeyDir: dir, RetentionPeriod: time.Hour}
+	}
+	first, err := NewManager(config())
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := NewManager(config())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.current().ID != second.current().ID {
+		t.Fatalf("managers sharing %s use different keys", dir)
+	}
+
+	if err = first.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	signed, err := first.Sign(jwt.StandardClaims{Subject: "user"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	second.reloaded = time.Time{}
+	if _, err = jwt.Parse(signed, second.Keyfunc); err != nil {
+		t.Fatalf("token signed after rotation rejected by another manager: %v", err)
+	}
+	if len(second.JWKS().Keys) != 2 {
+		t.Fatalf("expected the retired and current key in the JWKS, got %d", len(second.JWKS().Keys))
+	}
+}
+
+func TestNewManagerRequiresSharedKeysOutsideDevelopment(t *testing.T) {
+	if _, err := NewManager(&Config{Algorithm: ALGORITHM_RS256}); err == nil {
+		t.Fatal("expected an error without a key file or key directory")
+	}
+	if _, err := NewManager(&Config{Algorithm: ALGORITHM_EDDSA, AllowEphemeral: true}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPrivateKeyFileCannotRotate(t *testing.T) {
+	_, err := NewManager(&Config{Algorithm: ALGORITHM_EDDSA, PrivateKeyFile: "unused.pem", RotationInterval: time.Hour})
+	if err == nil {
+		t.Fatal("expected rotation with a single key file to be refused")
+	}
+}
+
+func TestKeyDirRotatesOnceAcrossManagers(t *testing.T) {
+	dir := t.TempDir()
+	var managers []*Manager
+	for i := 0; i < 4; i++ {
+		manager, err := NewManager(&Config{Algorithm: ALGORITHM_EDDSA, KeyDir: dir, RetentionPeriod: time.Hour})
+		if err != nil {
+			t.Fatal(err)
+		}
+		managers = append(managers, manager)
+	}
+
+	var wg sync.WaitGroup
+	for _, manager := range managers {
+		wg.Add(1)
+		go func(manager *Manager) {
+			defer wg.Done()
+			if err := manager.Rotate(); err != nil {
+				t.Error(err)
+			}
+		}(manager)
+	}
+	wg.Wait()
+
+	keys, err := filepath.Glob(filepath.Join(dir, "*"+KEY_FILE_EXTENSION))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 2 {
+		t.Fatalf("expected a single rotation, found %d key files", len(keys))
+	}
+	for _, manager := range managers[1:] {
+		if manager.current().ID != managers[0].current().ID {
+			t.Fatal("managers disagree on the current key after rotation")
+		}
+	}
+}
+
+func TestStaleRotationLockIsBroken(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, ROTATION_LOCK_FILE)
+	if err := os.WriteFile(path, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	stale := time.Now().Add(-2 * ROTATION_LOCK_TIMEOUT)
+	if err := os.Chtimes(path, stale, stale); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewManager(&Config{Algorithm: ALGORITHM_EDDSA, KeyDir: dir, RetentionPeriod: time.Hour}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("expected the rotation lock to be released, got %v", err)
+	}
+}
@@ -0,0 +1,175 @@
+package lockout
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/th3khan/rest-web-sockets-with-go/models"
+)
+
+const (
+	DRIVER_MEMORY   = "memory"
+	DRIVER_DATABASE = "database"
+
+	ACCOUNT_KEY_PREFIX = "account:"
+	IP_KEY_PREFIX      = "ip:"
+)
+
+type Tracker interface {
+	Get(ctx context.Context, key string) (*models.LoginAttempts, error)
+	RecordFailure(ctx context.Context, key string, window time.Duration) (*models.LoginAttempts, error)
+	Release(ctx context.Context, key string) error
+	Reset(ctx context.Context, key string) error
+}
+
+type Config struct {
+	Driver           string
+	Window           time.Duration
+	FreeAttempts     int
+	IPFreeAttempts   int
+	BackoffBase      time.Duration
+	BackoffMax       time.Duration
+	LockoutThreshold int
+	LockoutDuration  time.Duration
+}
+
+type Decision struct {
+	Status     int
+	RetryAfter time.Duration
+}
+
+func (d *Decision) Allowed() bool {
+	return d.Status == http.StatusOK
+}
+
+type Guard struct {
+	config  *Config
+	tracker Tracker
+}
+
+func New(config *Config) (*Guard, error) {
+	if config.Window <= 0 {
+		config.Window = 15 * time.Minute
+	}
+	if config.FreeAttempts <= 0 {
+		config.FreeAttempts = 3
+	}
+	if config.IPFreeAttempts <= 0 {
+		config.IPFreeAttempts = 20
+	}
+	if config.BackoffBase <= 0 {
+		config.BackoffBase = time.Second
+	}
+	if config.BackoffMax <= 0 {
+		config.BackoffMax = 5 * time.Minute
+	}
+	if config.LockoutThreshold <= 0 {
+		config.LockoutThreshold = 10
+	}
+	if config.LockoutDuration <= 0 {
+		config.LockoutDuration = 15 * time.Minute
+	}
+	retention := config.Window
+	if config.LockoutDuration > retention {
+		retention = config.LockoutDuration
+	}
+	switch config.Driver {
+	case "", DRIVER_MEMORY:
+		return NewGuard(config, NewMemoryTracker(retention)), nil
+	case DRIVER_DATABASE:
+		return NewGuard(config, NewRepositoryTracker()), nil
+	}
+	return nil, fmt.Errorf("unknown lockout driver %s", config.Driver)
+}
+
+func NewGuard(config *Config, tracker Tracker) *Guard {
+	return &Guard{
+		config:  config,
+		tracker: tracker,
+	}
+}
+
+func (g *Guard) backoff(failures int, free int) time.Duration {
+	if failures < free {
+		return 0
+	}
+	delay := g.config.BackoffBase
+	for i := free; i < failures && delay < g.config.BackoffMax; i++ {
+		delay *= 2
+	}
+	if delay > g.config.BackoffMax {
+		delay = g.config.BackoffMax
+	}
+	return delay
+}
+
+func (g *Guard) decide(attempts *models.LoginAttempts, free int, lockable bool, now time.Time) *Decision {
+	if attempts == nil {
+		return &Decision{Status: http.StatusOK}
+	}
+	if lockable && attempts.Failures >= g.config.LockoutThreshold {
+		if wait := attempts.LastFailureAt.Add(g.config.LockoutDuration).Sub(now); wait > 0 {
+			return &Decision{Status: http.StatusLocked, RetryAfter: wait}
+		}
+	}
+	if now.Sub(attempts.LastFailureAt) >= g.config.Window {
+		return &Decision{Status: http.StatusOK}
+	}
+	if wait := attempts.LastFailureAt.Add(g.backoff(attempts.Failures, free)).Sub(now); wait > 0 {
+		return &Decision{Status: http.StatusTooManyRequests, RetryAfter: wait}
+	}
+	return &Decision{Status: http.StatusOK}
+}
+
+func (g *Guard) reserve(ctx context.Context, key string, free int, lockable bool, now time.Time) (*Decision, error) {
+	attempts, err := g.tracker.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if decision := g.decide(attempts, free, lockable, now); !decision.Allowed() {
+		return decision, nil
+	}
+	expected := 1
+	if attempts != nil && now.Sub(attempts.LastFailureAt) < g.config.Window {
+		expected = attempts.Failures + 1
+	}
+	reserved, err := g.tracker.RecordFailure(ctx, key, g.config.Window)
+	if err != nil {
+		return nil, err
+	}
+	if reserved.Failures <= free || reserved.Failures == expected {
+		return &Decision{Status: http.StatusOK}, nil
+	}
+	if err := g.tracker.Release(ctx, key); err != nil {
+		return nil, err
+	}
+	return g.decide(reserved, free, lockable, now), nil
+}
+
+func (g *Guard) Reserve(ctx context.Context, account string, ip string) (*Decision, error) {
+	now := time.Now()
+	decision, err := g.reserve(ctx, ACCOUNT_KEY_PREFIX+account, g.config.FreeAttempts, true, now)
+	if err != nil || !decision.Allowed() {
+		return decision, err
+	}
+	decision, err = g.reserve(ctx, IP_KEY_PREFIX+ip, g.config.IPFreeAttempts, false, now)
+	if err != nil || !decision.Allowed() {
+		if err := g.tracker.Release(ctx, ACCOUNT_KEY_PREFIX+account); err != nil {
+			return nil, err
+		}
+	}
+	return decision, err
+}
+
+func (g *Guard) Succeed(ctx context.Context, account string, ip string) error {
+	if err := g.tracker.Reset(ctx, ACCOUNT_KEY_PREFIX+account); err != nil {
+		return err
+	}
+	return g.tracker.Release(ctx, IP_KEY_PREFIX+ip)
+}
+
+func (g *Guard) Unlock(ctx context.Context, account string) error {
+	return g.tracker.Reset(ctx, ACCOUNT_KEY_PREFIX+account)
+}
@@ -0,0 +1,131 @@
+package lockout
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/th3khan/rest-web-sockets-with-go/models"
+)
+
+func newTestGuard(t *testing.T, config *Config) *Guard {
+	guard, err := New(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return guard
+}
+
+func TestDecideKeepsLockoutAfterWindow(t *testing.T) {
+	guard := newTestGuard(t, &Config{
+		Window:           time.Minute,
+		FreeAttempts:     3,
+		LockoutThreshold: 3,
+		LockoutDuration:  time.Hour,
+	})
+	now := time.Now()
+	attempts := &models.LoginAttempts{Failures: 5, LastFailureAt: now.Add(-2 * time.Minute)}
+
+	decision := guard.decide(attempts, guard.config.FreeAttempts, true, now)
+	if decision.Status != http.StatusLocked {
+		t.Fatalf("expected account to stay locked, got %d", decision.Status)
+	}
+	if decision.RetryAfter != 58*time.Minute {
+		t.Fatalf("expected 58m retry after, got %s", decision.RetryAfter)
+	}
+	if decision := guard.decide(attempts, guard.config.FreeAttempts, true, now.Add(time.Hour)); !decision.Allowed() {
+		t.Fatalf("expected lockout to expire, got %d", decision.Status)
+	}
+	if decision := guard.decide(attempts, guard.config.FreeAttempts, false, now); !decision.Allowed() {
+		t.Fatalf("expected ip key to ignore the lockout, got %d", decision.Status)
+	}
+}
+
+func TestReserveCountsAttemptsBeforeVerification(t *testing.T) {
+	guard := newTestGuard(t, &Config{
+		Window:           time.Minute,
+		FreeAttempts:     3,
+		BackoffBase:      time.Minute,
+		LockoutThreshold: 3,
+		LockoutDuration:  time.Hour,
+	})
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		decision, err := guard.Reserve(ctx, "user@example.com", "127.0.0.1")
+		if err != nil || !decision.Allowed() {
+			t.Fatalf("attempt %d: expected to be allowed, got %v %v", i+1, decision, err)
+		}
+	}
+	decision, err := guard.Reserve(ctx, "user@example.com", "127.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decision.Status != http.StatusLocked {
+		t.Fatalf("expected account to be locked, got %d", decision.Status)
+	}
+}
+
+func TestReserveIsAtomic(t *testing.T) {
+	guard := newTestGuard(t, &Config{
+		Window:           time.Minute,
+		FreeAttempts:     3,
+		IPFreeAttempts:   100,
+		BackoffBase:      time.Minute,
+		LockoutThreshold: 10,
+		LockoutDuration:  time.Hour,
+	})
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		allowed int
+	)
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			decision, err := guard.Reserve(context.Background(), "user@example.com", "127.0.0.1")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if decision.Allowed() {
+				mu.Lock()
+				allowed++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if allowed != 3 {
+		t.Fatalf("expected exactly 3 concurrent attempts to be allowed, got %d", allowed)
+	}
+	attempts, err := guard.tracker.Get(context.Background(), ACCOUNT_KEY_PREFIX+"user@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attempts.Failures != 3 {
+		t.Fatalf("expected rejected attempts to give their slot back, got %d failures", attempts.Failures)
+	}
+}
+
+func TestSucceedReleasesReservation(t *testing.T) {
+	guard := newTestGuard(t, &Config{})
+	ctx := context.Background()
+	if _, err := guard.Reserve(ctx, "user@example.com", "127.0.0.1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := guard.Succeed(ctx, "user@example.com", "127.0.0.1"); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{ACCOUNT_KEY_PREFIX + "user@example.com", IP_KEY_PREFIX + "127.0.0.1"} {
+		attempts, err := guard.tracker.Get(ctx, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if attempts != nil {
+			t.Fatalf("expected %s to be cleared, got %d failures", key, attempts.Failures)
+		}
+	}
+}
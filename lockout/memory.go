@@ -0,0 +1,85 @@
+package lockout
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/th3khan/rest-web-sockets-with-go/models"
+)
+
+type MemoryTracker struct {
+	mutex     *sync.Mutex
+	attempts  map[string]models.LoginAttempts
+	retention time.Duration
+	pruned    time.Time
+}
+
+func NewMemoryTracker(retention time.Duration) *MemoryTracker {
+	return &MemoryTracker{
+		mutex:     &sync.Mutex{},
+		attempts:  make(map[string]models.LoginAttempts),
+		retention: retention,
+		pruned:    time.Now(),
+	}
+}
+
+func (t *MemoryTracker) prune(now time.Time) {
+	if now.Sub(t.pruned) < t.retention {
+		return
+	}
+	t.pruned = now
+	for key, attempts := range t.attempts {
+		if now.Sub(attempts.LastFailureAt) >= t.retention {
+			delete(t.attempts, key)
+		}
+	}
+}
+
+func (t *MemoryTracker) Get(ctx context.Context, key string) (*models.LoginAttempts, error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	attempts, ok := t.attempts[key]
+	if !ok {
+		return nil, nil
+	}
+	return &attempts, nil
+}
+
+func (t *MemoryTracker) RecordFailure(ctx context.Context, key string, window time.Duration) (*models.LoginAttempts, error) {
+	now := time.Now()
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.prune(now)
+	attempts, ok := t.attempts[key]
+	if !ok || now.Sub(attempts.LastFailureAt) >= window {
+		attempts = models.LoginAttempts{Key: key}
+	}
+	attempts.Failures++
+	attempts.LastFailureAt = now
+	t.attempts[key] = attempts
+	return &attempts, nil
+}
+
+func (t *MemoryTracker) Release(ctx context.Context, key string) error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	attempts, ok := t.attempts[key]
+	if !ok {
+		return nil
+	}
+	attempts.Failures--
+	if attempts.Failures <= 0 {
+		delete(t.attempts, key)
+		return nil
+	}
+	t.attempts[key] = attempts
+	return nil
+}
+
+func (t *MemoryTracker) Reset(ctx context.Context, key string) error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	delete(t.attempts, key)
+	return nil
+}
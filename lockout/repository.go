@@ -0,0 +1,31 @@
+package lockout
+
+import (
+	"context"
+	"time"
+
+	"github.com/th3khan/rest-web-sockets-with-go/models"
+	"github.com/th3khan/rest-web-sockets-with-go/repositories"
+)
+
+type RepositoryTracker struct{}
+
+func NewRepositoryTracker() *RepositoryTracker {
+	return &RepositoryTracker{}
+}
+
+func (t *RepositoryTracker) Get(ctx context.Context, key string) (*models.LoginAttempts, error) {
+	return repositories.GetLoginAttempts(ctx, key)
+}
+
+func (t *RepositoryTracker) RecordFailure(ctx context.Context, key string, window time.Duration) (*models.LoginAttempts, error) {
+	return repositories.RecordLoginFailure(ctx, key, window)
+}
+
+func (t *RepositoryTracker) Release(ctx context.Context, key string) error {
+	return repositories.ReleaseLoginAttempt(ctx, key)
+}
+
+func (t *RepositoryTracker) Reset(ctx context.Context, key string) error {
+	return repositories.ResetLoginAttempts(ctx, key)
+}
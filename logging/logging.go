@@ -0,0 +1,44 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+type contextKey struct{}
+
+type RequestInfo struct {
+	ID     string
+	UserID string
+}
+
+func New(level string) *slog.Logger {
+	var lvl slog.Level
+	switch strings.ToLower(level) {
+	case "debug":
+		lvl = slog.LevelDebug
+	case "warn":
+		lvl = slog.LevelWarn
+	case "error":
+		lvl = slog.LevelError
+	default:
+		lvl = slog.LevelInfo
+	}
+	return slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
+		Level: lvl,
+	}))
+}
+
+func WithRequestInfo(ctx context.Context, info *RequestInfo) context.Context {
+	return context.WithValue(ctx, contextKey{}, info)
+}
+
+func RequestInfoFromContext(ctx context.Context) *RequestInfo {
+	info, ok := ctx.Value(contextKey{}).(*RequestInfo)
+	if !ok {
+		return &RequestInfo{}
+	}
+	return info
+}
@@ -0,0 +1,48 @@
+package mailer
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/segmentio/ksuid"
+)
+
+type FileMailer struct {
+	dir  string
+	from string
+}
+
+func NewFileMailer(dir string, from string) (*FileMailer, error) {
+	if dir == "" {
+		dir = "mail"
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &FileMailer{
+		dir:  dir,
+		from: from,
+	}, nil
+}
+
+func (m *FileMailer) Send(ctx context.Context, message *Message) error {
+	path := filepath.Join(m.dir, ksuid.New().String()+".eml")
+	return os.WriteFile(path, format(m.from, message), 0o644)
+}
+
+type LogMailer struct {
+	logger *slog.Logger
+}
+
+func NewLogMailer(logger *slog.Logger) *LogMailer {
+	return &LogMailer{
+		logger: logger,
+	}
+}
+
+func (m *LogMailer) Send(ctx context.Context, message *Message) error {
+	m.logger.Info("mail", "to", message.To, "subject", message.Subject, "body", message.Body)
+	return nil
+}
@@ -0,0 +1,48 @@
+package mailer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+)
+
+const (
+	DRIVER_SMTP = "smtp"
+	DRIVER_FILE = "file"
+	DRIVER_LOG  = "log"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type Mailer interface {
+	Send(ctx context.Context, message *Message) error
+}
+
+type Config struct {
+	Driver   string
+	From     string
+	Host     string
+	Port     int
+	Username string
+	Password string
+	Dir      string
+}
+
+func New(config *Config, logger *slog.Logger) (Mailer, error) {
+	switch config.Driver {
+	case "":
+		return nil, errors.New("mail driver is required")
+	case DRIVER_LOG:
+		return NewLogMailer(logger), nil
+	case DRIVER_FILE:
+		return NewFileMailer(config.Dir, config.From)
+	case DRIVER_SMTP:
+		return NewSMTPMailer(config), nil
+	}
+	return nil, fmt.Errorf("unknown mail driver %s", config.Driver)
+}
@@ -5,12 +5,27 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/joho/godotenv"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/th3khan/rest-web-sockets-with-go/database"
+	"github.com/th3khan/rest-web-sockets-with-go/docs"
 	"github.com/th3khan/rest-web-sockets-with-go/handlers"
+	"github.com/th3khan/rest-web-sockets-with-go/idempotency"
+	"github.com/th3khan/rest-web-sockets-with-go/keys"
+	"github.com/th3khan/rest-web-sockets-with-go/lockout"
+	"github.com/th3khan/rest-web-sockets-with-go/mailer"
 	"github.com/th3khan/rest-web-sockets-with-go/middlewares"
+	"github.com/th3khan/rest-web-sockets-with-go/models"
+	"github.com/th3khan/rest-web-sockets-with-go/oidc"
+	"github.com/th3khan/rest-web-sockets-with-go/passwords"
+	"github.com/th3khan/rest-web-sockets-with-go/ratelimit"
 	"github.com/th3khan/rest-web-sockets-with-go/server"
+	"github.com/th3khan/rest-web-sockets-with-go/tracing"
 )
 
 func main() {
@@ -23,9 +38,92 @@ func main() {
 	DATABASE_URL := os.Getenv("DATABASE_URL")
 
 	s, err := server.NewServer(context.Background(), &server.Config{
-		Port:        PORT,
-		JWTSecret:   JWT_SECRET,
-		DataBaseUrl: DATABASE_URL,
+		Environment:           os.Getenv("APP_ENV"),
+		Port:                  PORT,
+		JWTSecret:             JWT_SECRET,
+		DataBaseUrl:           DATABASE_URL,
+		LogLevel:              os.Getenv("LOG_LEVEL"),
+		AccessTokenTTL:        getEnvDuration("ACCESS_TOKEN_TTL", 15*time.Minute),
+		RefreshTokenTTL:       getEnvDuration("REFRESH_TOKEN_TTL", 30*24*time.Hour),
+		RevocationTTL:         getEnvDuration("REVOCATION_CACHE_TTL", 10*time.Second),
+		AppBaseURL:            os.Getenv("APP_BASE_URL"),
+		EmailVerificationTTL:  getEnvDuration("EMAIL_VERIFICATION_TTL", 24*time.Hour),
+		PasswordResetTTL:      getEnvDuration("PASSWORD_RESET_TTL", time.Hour),
+		MFAChallengeTTL:       getEnvDuration("MFA_CHALLENGE_TTL", 5*time.Minute),
+		MFAIssuer:             os.Getenv("MFA_ISSUER"),
+		RequireVerifiedEmail:  os.Getenv("REQUIRE_VERIFIED_EMAIL"),
+		LegacyAPIDeprecatedAt: getEnvTime("LEGACY_API_DEPRECATED_AT", time.Time{}),
+		LegacyAPISunsetAt:     getEnvTime("LEGACY_API_SUNSET_AT", time.Time{}),
+		Mail: mailer.Config{
+			Driver:   os.Getenv("MAIL_DRIVER"),
+			From:     os.Getenv("MAIL_FROM"),
+			Host:     os.Getenv("SMTP_HOST"),
+			Port:     getEnvInt("SMTP_PORT", 25),
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			Dir:      os.Getenv("MAIL_DIR"),
+		},
+		Passwords: passwords.Config{
+			MinLength:        getEnvInt("PASSWORD_MIN_LENGTH", 8),
+			RequireMixedCase: getEnvBool("PASSWORD_REQUIRE_MIXED_CASE", false),
+			RequireDigit:     getEnvBool("PASSWORD_REQUIRE_DIGIT", false),
+			RequireSymbol:    getEnvBool("PASSWORD_REQUIRE_SYMBOL", false),
+			BreachedListFile: os.Getenv("PASSWORD_BREACHED_LIST_FILE"),
+			Algorithm:        os.Getenv("PASSWORD_HASH_ALGORITHM"),
+			BcryptCost:       getEnvInt("BCRYPT_COST", 0),
+			Argon2Time:       uint32(getEnvInt("ARGON2_TIME", 0)),
+			Argon2Memory:     uint32(getEnvInt("ARGON2_MEMORY_KB", 0)),
+			Argon2Threads:    uint8(getEnvInt("ARGON2_THREADS", 0)),
+		},
+		Keys: keys.Config{
+			Algorithm:        os.Getenv("JWT_ALGORITHM"),
+			PrivateKeyFile:   os.Getenv("JWT_PRIVATE_KEY_FILE"),
+			KeyDir:           os.Getenv("JWT_KEY_DIR"),
+			RotationInterval: getEnvDuration("JWT_KEY_ROTATION_INTERVAL", 0),
+		},
+		DataBase: database.MySQLConfig{
+			MaxOpenConns:    getEnvInt("DB_MAX_OPEN_CONNS", 25),
+			MaxIdleConns:    getEnvInt("DB_MAX_IDLE_CONNS", 25),
+			ConnMaxLifetime: getEnvDuration("DB_CONN_MAX_LIFETIME", 5*time.Minute),
+			ConnMaxIdleTime: getEnvDuration("DB_CONN_MAX_IDLE_TIME", time.Minute),
+			PingRetries:     getEnvInt("DB_PING_RETRIES", 5),
+			PingBackoff:     getEnvDuration("DB_PING_BACKOFF", time.Second),
+			AutoMigrate:     getEnvBool("DB_AUTO_MIGRATE", true),
+		},
+		Tracing: tracing.Config{
+			Exporter:     os.Getenv("TRACING_EXPORTER"),
+			OTLPEndpoint: os.Getenv("TRACING_OTLP_ENDPOINT"),
+			OTLPInsecure: getEnvBool("TRACING_OTLP_INSECURE", false),
+			ServiceName:  os.Getenv("TRACING_SERVICE_NAME"),
+		},
+		OIDC: oidc.Config{
+			IssuerURL:    os.Getenv("OIDC_ISSUER_URL"),
+			ClientID:     os.Getenv("OIDC_CLIENT_ID"),
+			ClientSecret: os.Getenv("OIDC_CLIENT_SECRET"),
+			RedirectURL:  os.Getenv("OIDC_REDIRECT_URL"),
+			Scopes:       strings.Fields(os.Getenv("OIDC_SCOPES")),
+		},
+		Lockout: lockout.Config{
+			Driver:           os.Getenv("LOGIN_LOCKOUT_DRIVER"),
+			Window:           getEnvDuration("LOGIN_ATTEMPT_WINDOW", 15*time.Minute),
+			FreeAttempts:     getEnvInt("LOGIN_FREE_ATTEMPTS", 3),
+			IPFreeAttempts:   getEnvInt("LOGIN_IP_FREE_ATTEMPTS", 20),
+			BackoffBase:      getEnvDuration("LOGIN_BACKOFF_BASE", time.Second),
+			BackoffMax:       getEnvDuration("LOGIN_BACKOFF_MAX", 5*time.Minute),
+			LockoutThreshold: getEnvInt("LOGIN_LOCKOUT_THRESHOLD", 10),
+			LockoutDuration:  getEnvDuration("LOGIN_LOCKOUT_DURATION", 15*time.Minute),
+		},
+		RateLimit: ratelimit.Config{
+			Driver:       os.Getenv("RATE_LIMIT_DRIVER"),
+			DefaultLimit: os.Getenv("RATE_LIMIT_DEFAULT"),
+			RouteLimits:  os.Getenv("RATE_LIMIT_ROUTES"),
+			IPLimit:      os.Getenv("RATE_LIMIT_IP"),
+		},
+		Idempotency: idempotency.Config{
+			Driver: os.Getenv("IDEMPOTENCY_DRIVER"),
+			TTL:    getEnvDuration("IDEMPOTENCY_KEY_TTL", idempotency.DEFAULT_TTL),
+			Lease:  getEnvDuration("IDEMPOTENCY_LEASE", idempotency.DEFAULT_LEASE),
+		},
 	})
 
 	if err != nil {
@@ -35,16 +133,101 @@ func main() {
 }
 
 func BindRouter(s server.Server, r *mux.Router) {
+	r.Use(middlewares.TracingMiddleware)
+	r.Use(middlewares.MetricsMiddleware)
+	r.Use(middlewares.LoggingMiddleware(s))
+	r.Use(middlewares.DeprecationMiddleware(s))
+	r.Use(middlewares.IPRateLimitMiddleware(s))
 	r.Use(middlewares.CheckAuthMiddleware(s))
+	r.Use(middlewares.RateLimitMiddleware(s))
+	r.Use(middlewares.IdempotencyMiddleware(s))
 	r.HandleFunc("/", handlers.HomeHandler(s)).Methods(http.MethodGet)
-	r.HandleFunc("/signup", handlers.SignUpHandler(s)).Methods(http.MethodPost)
-	r.HandleFunc("/login", handlers.LoginHanlder(s)).Methods(http.MethodPost)
-	r.HandleFunc("/me", handlers.MeHandler(s)).Methods(http.MethodGet)
-	r.HandleFunc("/posts", handlers.InsertPostHandler(s)).Methods(http.MethodPost)
-	r.HandleFunc("/posts/{id}", handlers.GetPostByIdHandler(s)).Methods(http.MethodGet)
-	r.HandleFunc("/posts/{id}", handlers.UpdatePostHandler(s)).Methods(http.MethodPut)
-	r.HandleFunc("/posts/{id}", handlers.DeletePostHandler(s)).Methods(http.MethodDelete)
-	r.HandleFunc("/posts", handlers.ListPostHandler(s)).Methods(http.MethodGet)
+	middlewares.Public(r.HandleFunc("/healthz", handlers.HealthzHandler(s)).Methods(http.MethodGet))
+	middlewares.Public(r.HandleFunc("/readyz", handlers.ReadyzHandler(s)).Methods(http.MethodGet))
+	middlewares.Public(r.Handle("/metrics", promhttp.Handler()).Methods(http.MethodGet))
+	middlewares.Public(r.HandleFunc("/.well-known/jwks.json", handlers.JWKSHandler(s)).Methods(http.MethodGet))
+	middlewares.Public(r.HandleFunc("/openapi.json", docs.SpecHandler()).Methods(http.MethodGet))
+	middlewares.Public(r.HandleFunc("/asyncapi.json", docs.AsyncAPIHandler()).Methods(http.MethodGet))
+	middlewares.Public(r.HandleFunc("/docs", docs.UIHandler()).Methods(http.MethodGet))
+	middlewares.Public(r.HandleFunc("/docs/{asset}", docs.AssetHandler()).Methods(http.MethodGet))
+
+	BindV1(s, r.PathPrefix("/v1").Subrouter())
+
+	legacy := r.NewRoute().Subrouter()
+	BindV1(s, legacy)
+	if err := middlewares.Deprecated("/v1", legacy); err != nil {
+		log.Fatal("Error", err)
+	}
 
 	r.HandleFunc("/ws", s.Hub().HandleWebSocket)
 }
+
+func BindV1(s server.Server, r *mux.Router) {
+	middlewares.Public(r.HandleFunc("/signup", handlers.SignUpHandler(s)).Methods(http.MethodPost))
+	middlewares.Public(r.HandleFunc("/login", handlers.LoginHanlder(s)).Methods(http.MethodPost))
+	middlewares.Public(r.HandleFunc("/login/mfa", handlers.LoginMFAHandler(s)).Methods(http.MethodPost))
+	middlewares.Public(r.HandleFunc("/auth/oidc/login", handlers.OIDCLoginHandler(s)).Methods(http.MethodGet))
+	middlewares.Public(r.HandleFunc("/auth/oidc/callback", handlers.OIDCCallbackHandler(s)).Methods(http.MethodGet))
+	middlewares.Public(r.HandleFunc("/token/refresh", handlers.RefreshTokenHandler(s)).Methods(http.MethodPost))
+	middlewares.Public(r.HandleFunc("/verify-email", handlers.VerifyEmailHandler(s)).Methods(http.MethodPost))
+	middlewares.Public(r.HandleFunc("/verify-email/resend", handlers.ResendVerificationEmailHandler(s)).Methods(http.MethodPost))
+	middlewares.Public(r.HandleFunc("/password/forgot", handlers.ForgotPasswordHandler(s)).Methods(http.MethodPost))
+	middlewares.Public(r.HandleFunc("/password/reset", handlers.ResetPasswordHandler(s)).Methods(http.MethodPost))
+	r.HandleFunc("/logout", handlers.LogoutHandler(s)).Methods(http.MethodPost)
+	r.HandleFunc("/logout/all", handlers.LogoutAllHandler(s)).Methods(http.MethodPost)
+	r.HandleFunc("/me", handlers.MeHandler(s)).Methods(http.MethodGet)
+	r.HandleFunc("/me/password", handlers.ChangePasswordHandler(s)).Methods(http.MethodPut)
+	r.HandleFunc("/me/mfa/totp", handlers.EnrollTOTPHandler(s)).Methods(http.MethodPost)
+	r.HandleFunc("/me/mfa/totp/confirm", handlers.ConfirmTOTPHandler(s)).Methods(http.MethodPost)
+	r.HandleFunc("/me/mfa/totp", handlers.DisableTOTPHandler(s)).Methods(http.MethodDelete)
+	r.HandleFunc("/me/sessions", handlers.ListSessionsHandler(s)).Methods(http.MethodGet)
+	r.HandleFunc("/me/sessions/{id}", handlers.RevokeSessionHandler(s)).Methods(http.MethodDelete)
+	r.HandleFunc("/me/api-keys", handlers.CreateAPIKeyHandler(s)).Methods(http.MethodPost)
+	r.HandleFunc("/me/api-keys", handlers.ListAPIKeysHandler(s)).Methods(http.MethodGet)
+	r.HandleFunc("/me/api-keys/{id}", handlers.RevokeAPIKeyHandler(s)).Methods(http.MethodDelete)
+	r.HandleFunc("/me/identities/oidc", handlers.OIDCLinkHandler(s)).Methods(http.MethodPost)
+	middlewares.Scoped(models.SCOPE_POSTS_WRITE, r.HandleFunc("/posts", handlers.InsertPostHandler(s)).Methods(http.MethodPost))
+	middlewares.Scoped(models.SCOPE_POSTS_READ, r.HandleFunc("/posts/{id}", handlers.GetPostByIdHandler(s)).Methods(http.MethodGet))
+	middlewares.Scoped(models.SCOPE_POSTS_WRITE, r.HandleFunc("/posts/{id}", handlers.UpdatePostHandler(s)).Methods(http.MethodPut))
+	middlewares.Scoped(models.SCOPE_POSTS_WRITE, r.HandleFunc("/posts/{id}", handlers.DeletePostHandler(s)).Methods(http.MethodDelete))
+	middlewares.Scoped(models.SCOPE_POSTS_READ, r.HandleFunc("/posts", handlers.ListPostHandler(s)).Methods(http.MethodGet))
+
+	admin := r.PathPrefix("/admin").Subrouter()
+	admin.Use(middlewares.RequirePermission(models.PERMISSION_USERS_MANAGE))
+	admin.HandleFunc("/users", handlers.AdminListUsersHandler(s)).Methods(http.MethodGet)
+	admin.HandleFunc("/users/{id}", handlers.AdminGetUserHandler(s)).Methods(http.MethodGet)
+	admin.HandleFunc("/users/{id}/roles", handlers.AdminUpdateUserRolesHandler(s)).Methods(http.MethodPut)
+	admin.HandleFunc("/users/{id}/lockout", handlers.AdminUnlockUserHandler(s)).Methods(http.MethodDelete)
+}
+
+func getEnvInt(key string, fallback int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+func getEnvBool(key string, fallback bool) bool {
+	value, err := strconv.ParseBool(os.Getenv(key))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	value, err := time.ParseDuration(os.Getenv(key))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+func getEnvTime(key string, fallback time.Time) time.Time {
+	value, err := time.Parse(time.RFC3339, os.Getenv(key))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/th3khan/rest-web-sockets-with-go/docs"
+	"github.com/th3khan/rest-web-sockets-with-go/server"
+)
+
+func newTestRouter(t *testing.T) *mux.Router {
+	s, err := server.NewServer(context.Background(), &server.Config{
+		Port:        ":0",
+		DataBaseUrl: "test",
+		JWTSecret:   "test-secret",
+		Environment: server.ENVIRONMENT_DEVELOPMENT,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := mux.NewRouter()
+	BindRouter(s, r)
+	return r
+}
+
+func TestRoutesMatchOpenAPIDocument(t *testing.T) {
+	divergences, err := docs.CheckRoutes(newTestRouter(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, divergence := range divergences {
+		t.Error(divergence)
+	}
+}
+
+func TestDocsAssetsAreServedLocally(t *testing.T) {
+	r := newTestRouter(t)
+	for _, path := range []string{"/docs", "/docs/ui.css", "/docs/ui.js"} {
+		recorder := httptest.NewRecorder()
+		r.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+		if recorder.Code != http.StatusOK {
+			t.Errorf("%s: expected 200, got %d", path, recorder.Code)
+		}
+	}
+	recorder := httptest.NewRecorder()
+	r.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/docs/missing.js", nil))
+	if recorder.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for unknown asset, got %d", recorder.Code)
+	}
+}
@@ -0,0 +1,58 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/th3khan/rest-web-sockets-with-go/websocket"
+)
+
+var (
+	HTTPRequestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "Total number of HTTP requests by route, method and status code.",
+	}, []string{"route", "method", "code"})
+
+	HTTPRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "HTTP request latency by route, method and status code.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"route", "method", "code"})
+
+	RepositoryCallDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "repository_call_duration_seconds",
+		Help:    "Repository call latency by method and result.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "result"})
+)
+
+func init() {
+	prometheus.MustRegister(HTTPRequestsTotal, HTTPRequestDuration, RepositoryCallDuration)
+}
+
+func RegisterHub(hub *websocket.Hub) {
+	prometheus.MustRegister(
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "websocket_connected_clients",
+			Help: "Number of websocket clients connected to the hub.",
+		}, func() float64 {
+			return float64(hub.ClientCount())
+		}),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "websocket_subscriptions",
+			Help: "Number of event subscriptions held by connected websocket clients.",
+		}, func() float64 {
+			return float64(hub.SubscriptionCount())
+		}),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "websocket_broadcast_queue_depth",
+			Help: "Number of messages waiting in client outbound queues.",
+		}, func() float64 {
+			return float64(hub.QueueDepth())
+		}),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Name: "websocket_dropped_messages_total",
+			Help: "Number of messages dropped because a client queue was full.",
+		}, func() float64 {
+			return float64(hub.DroppedMessages())
+		}),
+	)
+}
@@ -0,0 +1,331 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/th3khan/rest-web-sockets-with-go/models"
+	"github.com/th3khan/rest-web-sockets-with-go/repositories"
+)
+
+type InstrumentedRepository struct {
+	next repositories.Repository
+}
+
+func NewInstrumentedRepository(next repositories.Repository) *InstrumentedRepository {
+	return &InstrumentedRepository{
+		next: next,
+	}
+}
+
+func observe(method string, start time.Time, err *error) {
+	result := "ok"
+	if *err != nil {
+		result = "error"
+	}
+	RepositoryCallDuration.WithLabelValues(method, result).Observe(time.Since(start).Seconds())
+}
+
+func (r *InstrumentedRepository) InsertUser(ctx context.Context, user *models.User) (err error) {
+	defer observe("InsertUser", time.Now(), &err)
+	return r.next.InsertUser(ctx, user)
+}
+
+func (r *InstrumentedRepository) GetUserById(ctx context.Context, id string) (user *models.User, err error) {
+	defer observe("GetUserById", time.Now(), &err)
+	return r.next.GetUserById(ctx, id)
+}
+
+func (r *InstrumentedRepository) GetUserByEmail(ctx context.Context, email string) (user *models.User, err error) {
+	defer observe("GetUserByEmail", time.Now(), &err)
+	return r.next.GetUserByEmail(ctx, email)
+}
+
+func (r *InstrumentedRepository) InsertPost(ctx context.Context, post *models.Post) (err error) {
+	defer observe("InsertPost", time.Now(), &err)
+	return r.next.InsertPost(ctx, post)
+}
+
+func (r *InstrumentedRepository) GetPostById(ctx context.Context, id string) (post *models.Post, err error) {
+	defer observe("GetPostById", time.Now(), &err)
+	return r.next.GetPostById(ctx, id)
+}
+
+func (r *InstrumentedRepository) UpdatePost(ctx context.Context, post *models.Post) (err error) {
+	defer observe("UpdatePost", time.Now(), &err)
+	return r.next.UpdatePost(ctx, post)
+}
+
+func (r *InstrumentedRepository) DeletePost(ctx context.Context, id string, userId string) (err error) {
+	defer observe("DeletePost", time.Now(), &err)
+	return r.next.DeletePost(ctx, id, userId)
+}
+
+func (r *InstrumentedRepository) ListPosts(ctx context.Context, page uint64) (posts []*models.Post, err error) {
+	defer observe("ListPosts", time.Now(), &err)
+	return r.next.ListPosts(ctx, page)
+}
+
+func (r *InstrumentedRepository) InsertRefreshToken(ctx context.Context, token *models.RefreshToken) (err error) {
+	defer observe("InsertRefreshToken", time.Now(), &err)
+	return r.next.InsertRefreshToken(ctx, token)
+}
+
+func (r *InstrumentedRepository) GetRefreshTokenByHash(ctx context.Context, hash string) (token *models.RefreshToken, err error) {
+	defer observe("GetRefreshTokenByHash", time.Now(), &err)
+	return r.next.GetRefreshTokenByHash(ctx, hash)
+}
+
+func (r *InstrumentedRepository) RevokeRefreshToken(ctx context.Context, id string, replacedBy string) (revoked bool, err error) {
+	defer observe("RevokeRefreshToken", time.Now(), &err)
+	return r.next.RevokeRefreshToken(ctx, id, replacedBy)
+}
+
+func (r *InstrumentedRepository) RevokeRefreshTokenFamily(ctx context.Context, familyID string) (err error) {
+	defer observe("RevokeRefreshTokenFamily", time.Now(), &err)
+	return r.next.RevokeRefreshTokenFamily(ctx, familyID)
+}
+
+func (r *InstrumentedRepository) InsertRevokedToken(ctx context.Context, token *models.RevokedToken) (err error) {
+	defer observe("InsertRevokedToken", time.Now(), &err)
+	return r.next.InsertRevokedToken(ctx, token)
+}
+
+func (r *InstrumentedRepository) IsTokenRevoked(ctx context.Context, id string) (revoked bool, err error) {
+	defer observe("IsTokenRevoked", time.Now(), &err)
+	return r.next.IsTokenRevoked(ctx, id)
+}
+
+func (r *InstrumentedRepository) RevokeUserTokens(ctx context.Context, userID string) (err error) {
+	defer observe("RevokeUserTokens", time.Now(), &err)
+	return r.next.RevokeUserTokens(ctx, userID)
+}
+
+func (r *InstrumentedRepository) GetUserTokenVersion(ctx context.Context, userID string) (version int, err error) {
+	defer observe("GetUserTokenVersion", time.Now(), &err)
+	return r.next.GetUserTokenVersion(ctx, userID)
+}
+
+func (r *InstrumentedRepository) ListUsers(ctx context.Context, page uint64) (users []*models.User, err error) {
+	defer observe("ListUsers", time.Now(), &err)
+	return r.next.ListUsers(ctx, page)
+}
+
+func (r *InstrumentedRepository) GetUserRoles(ctx context.Context, userID string) (roles []string, err error) {
+	defer observe("GetUserRoles", time.Now(), &err)
+	return r.next.GetUserRoles(ctx, userID)
+}
+
+func (r *InstrumentedRepository) GetRolePermissions(ctx context.Context, roles []string) (permissions []string, err error) {
+	defer observe("GetRolePermissions", time.Now(), &err)
+	return r.next.GetRolePermissions(ctx, roles)
+}
+
+func (r *InstrumentedRepository) SetUserRoles(ctx context.Context, userID string, roles []string) (err error) {
+	defer observe("SetUserRoles", time.Now(), &err)
+	return r.next.SetUserRoles(ctx, userID, roles)
+}
+
+func (r *InstrumentedRepository) MarkEmailVerified(ctx context.Context, userID string) (err error) {
+	defer observe("MarkEmailVerified", time.Now(), &err)
+	return r.next.MarkEmailVerified(ctx, userID)
+}
+
+func (r *InstrumentedRepository) UpdateUserPassword(ctx context.Context, userID string, password string) (err error) {
+	defer observe("UpdateUserPassword", time.Now(), &err)
+	return r.next.UpdateUserPassword(ctx, userID, password)
+}
+
+func (r *InstrumentedRepository) InsertPasswordResetToken(ctx context.Context, token *models.PasswordResetToken) (err error) {
+	defer observe("InsertPasswordResetToken", time.Now(), &err)
+	return r.next.InsertPasswordResetToken(ctx, token)
+}
+
+func (r *InstrumentedRepository) GetPasswordResetTokenByHash(ctx context.Context, hash string) (token *models.PasswordResetToken, err error) {
+	defer observe("GetPasswordResetTokenByHash", time.Now(), &err)
+	return r.next.GetPasswordResetTokenByHash(ctx, hash)
+}
+
+func (r *InstrumentedRepository) MarkPasswordResetTokenUsed(ctx context.Context, id string) (used bool, err error) {
+	defer observe("MarkPasswordResetTokenUsed", time.Now(), &err)
+	return r.next.MarkPasswordResetTokenUsed(ctx, id)
+}
+
+func (r *InstrumentedRepository) GetTOTP(ctx context.Context, userID string) (totp *models.TOTP, err error) {
+	defer observe("GetTOTP", time.Now(), &err)
+	return r.next.GetTOTP(ctx, userID)
+}
+
+func (r *InstrumentedRepository) UpsertTOTP(ctx context.Context, totp *models.TOTP) (err error) {
+	defer observe("UpsertTOTP", time.Now(), &err)
+	return r.next.UpsertTOTP(ctx, totp)
+}
+
+func (r *InstrumentedRepository) ConfirmTOTP(ctx context.Context, userID string) (err error) {
+	defer observe("ConfirmTOTP", time.Now(), &err)
+	return r.next.ConfirmTOTP(ctx, userID)
+}
+
+func (r *InstrumentedRepository) DeleteTOTP(ctx context.Context, userID string) (err error) {
+	defer observe("DeleteTOTP", time.Now(), &err)
+	return r.next.DeleteTOTP(ctx, userID)
+}
+
+func (r *InstrumentedRepository) UseTOTPStep(ctx context.Context, userID string, step int64) (used bool, err error) {
+	defer observe("UseTOTPStep", time.Now(), &err)
+	return r.next.UseTOTPStep(ctx, userID, step)
+}
+
+func (r *InstrumentedRepository) ReplaceRecoveryCodes(ctx context.Context, userID string, hashes []string) (err error) {
+	defer observe("ReplaceRecoveryCodes", time.Now(), &err)
+	return r.next.ReplaceRecoveryCodes(ctx, userID, hashes)
+}
+
+func (r *InstrumentedRepository) UseRecoveryCode(ctx context.Context, userID string, hash string) (used bool, err error) {
+	defer observe("UseRecoveryCode", time.Now(), &err)
+	return r.next.UseRecoveryCode(ctx, userID, hash)
+}
+
+func (r *InstrumentedRepository) InsertExternalIdentity(ctx context.Context, identity *models.ExternalIdentity) (err error) {
+	defer observe("InsertExternalIdentity", time.Now(), &err)
+	return r.next.InsertExternalIdentity(ctx, identity)
+}
+
+func (r *InstrumentedRepository) GetExternalIdentity(ctx context.Context, provider string, subject string) (identity *models.ExternalIdentity, err error) {
+	defer observe("GetExternalIdentity", time.Now(), &err)
+	return r.next.GetExternalIdentity(ctx, provider, subject)
+}
+
+func (r *InstrumentedRepository) InsertAPIKey(ctx context.Context, key *models.APIKey) (err error) {
+	defer observe("InsertAPIKey", time.Now(), &err)
+	return r.next.InsertAPIKey(ctx, key)
+}
+
+func (r *InstrumentedRepository) GetAPIKeyByHash(ctx context.Context, hash string) (key *models.APIKey, err error) {
+	defer observe("GetAPIKeyByHash", time.Now(), &err)
+	return r.next.GetAPIKeyByHash(ctx, hash)
+}
+
+func (r *InstrumentedRepository) ListAPIKeys(ctx context.Context, userID string) (keys []*models.APIKey, err error) {
+	defer observe("ListAPIKeys", time.Now(), &err)
+	return r.next.ListAPIKeys(ctx, userID)
+}
+
+func (r *InstrumentedRepository) RevokeAPIKey(ctx context.Context, id string, userID string) (revoked bool, err error) {
+	defer observe("RevokeAPIKey", time.Now(), &err)
+	return r.next.RevokeAPIKey(ctx, id, userID)
+}
+
+func (r *InstrumentedRepository) TouchAPIKey(ctx context.Context, id string) (err error) {
+	defer observe("TouchAPIKey", time.Now(), &err)
+	return r.next.TouchAPIKey(ctx, id)
+}
+
+func (r *InstrumentedRepository) GetLoginAttempts(ctx context.Context, key string) (attempts *models.LoginAttempts, err error) {
+	defer observe("GetLoginAttempts", time.Now(), &err)
+	return r.next.GetLoginAttempts(ctx, key)
+}
+
+func (r *InstrumentedRepository) RecordLoginFailure(ctx context.Context, key string, window time.Duration) (attempts *models.LoginAttempts, err error) {
+	defer observe("RecordLoginFailure", time.Now(), &err)
+	return r.next.RecordLoginFailure(ctx, key, window)
+}
+
+func (r *InstrumentedRepository) ResetLoginAttempts(ctx context.Context, key string) (err error) {
+	defer observe("ResetLoginAttempts", time.Now(), &err)
+	return r.next.ResetLoginAttempts(ctx, key)
+}
+
+func (r *InstrumentedRepository) ReleaseLoginAttempt(ctx context.Context, key string) (err error) {
+	defer observe("ReleaseLoginAttempt", time.Now(), &err)
+	return r.next.ReleaseLoginAttempt(ctx, key)
+}
+
+func (r *InstrumentedRepository) InsertSession(ctx context.Context, session *models.Session) (err error) {
+	defer observe("InsertSession", time.Now(), &err)
+	return r.next.InsertSession(ctx, session)
+}
+
+func (r *InstrumentedRepository) RefreshSession(ctx context.Context, id string, ip string, expiresAt time.Time) (err error) {
+	defer observe("RefreshSession", time.Now(), &err)
+	return r.next.RefreshSession(ctx, id, ip, expiresAt)
+}
+
+func (r *InstrumentedRepository) TouchSession(ctx context.Context, id string) (err error) {
+	defer observe("TouchSession", time.Now(), &err)
+	return r.next.TouchSession(ctx, id)
+}
+
+func (r *InstrumentedRepository) ListSessions(ctx context.Context, userID string) (sessions []*models.Session, err error) {
+	defer observe("ListSessions", time.Now(), &err)
+	return r.next.ListSessions(ctx, userID)
+}
+
+func (r *InstrumentedRepository) RevokeSession(ctx context.Context, id string, userID string) (revoked bool, err error) {
+	defer observe("RevokeSession", time.Now(), &err)
+	return r.next.RevokeSession(ctx, id, userID)
+}
+
+func (r *InstrumentedRepository) IsSessionRevoked(ctx context.Context, id string) (revoked bool, err error) {
+	defer observe("IsSessionRevoked", time.Now(), &err)
+	return r.next.IsSessionRevoked(ctx, id)
+}
+
+func (r *InstrumentedRepository) TakeRateLimitToken(ctx context.Context, key string, capacity float64, rate float64) (tokens float64, allowed bool, err error) {
+	defer observe("TakeRateLimitToken", time.Now(), &err)
+	return r.next.TakeRateLimitToken(ctx, key, capacity, rate)
+}
+
+func (r *InstrumentedRepository) ReserveIdempotencyKey(ctx context.Context, record *models.IdempotencyRecord) (reserved bool, err error) {
+	defer observe("ReserveIdempotencyKey", time.Now(), &err)
+	return r.next.ReserveIdempotencyKey(ctx, record)
+}
+
+func (r *InstrumentedRepository) GetIdempotencyRecord(ctx context.Context, userID string, key string) (record *models.IdempotencyRecord, err error) {
+	defer observe("GetIdempotencyRecord", time.Now(), &err)
+	return r.next.GetIdempotencyRecord(ctx, userID, key)
+}
+
+func (r *InstrumentedRepository) CompleteIdempotencyRecord(ctx context.Context, record *models.IdempotencyRecord) (err error) {
+	defer observe("CompleteIdempotencyRecord", time.Now(), &err)
+	return r.next.CompleteIdempotencyRecord(ctx, record)
+}
+
+func (r *InstrumentedRepository) DeleteIdempotencyRecord(ctx context.Context, userID string, key string) (err error) {
+	defer observe("DeleteIdempotencyRecord", time.Now(), &err)
+	return r.next.DeleteIdempotencyRecord(ctx, userID, key)
+}
+
+func (r *InstrumentedRepository) InsertEmailVerificationToken(ctx context.Context, token *models.EmailVerificationToken) (err error) {
+	defer observe("InsertEmailVerificationToken", time.Now(), &err)
+	return r.next.InsertEmailVerificationToken(ctx, token)
+}
+
+func (r *InstrumentedRepository) GetEmailVerificationTokenByHash(ctx context.Context, hash string) (token *models.EmailVerificationToken, err error) {
+	defer observe("GetEmailVerificationTokenByHash", time.Now(), &err)
+	return r.next.GetEmailVerificationTokenByHash(ctx, hash)
+}
+
+func (r *InstrumentedRepository) MarkEmailVerificationTokenUsed(ctx context.Context, id string) (used bool, err error) {
+	defer observe("MarkEmailVerificationTokenUsed", time.Now(), &err)
+	return r.next.MarkEmailVerificationTokenUsed(ctx, id)
+}
+
+func (r *InstrumentedRepository) InvalidatePasswordResetTokens(ctx context.Context, userID string) (err error) {
+	defer observe("InvalidatePasswordResetTokens", time.Now(), &err)
+	return r.next.InvalidatePasswordResetTokens(ctx, userID)
+}
+
+func (r *InstrumentedRepository) Ping(ctx context.Context) (err error) {
+	defer observe("Ping", time.Now(), &err)
+	return r.next.Ping(ctx)
+}
+
+func (r *InstrumentedRepository) SchemaVersion(ctx context.Context) (version int, err error) {
+	defer observe("SchemaVersion", time.Now(), &err)
+	return r.next.SchemaVersion(ctx)
+}
+
+func (r *InstrumentedRepository) Close() error {
+	return r.next.Close()
+}
@@ -3,41 +3,155 @@ package middlewares
 import (
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/golang-jwt/jwt"
+	"github.com/gorilla/mux"
+	"github.com/th3khan/rest-web-sockets-with-go/auth"
+	"github.com/th3khan/rest-web-sockets-with-go/logging"
 	"github.com/th3khan/rest-web-sockets-with-go/models"
+	"github.com/th3khan/rest-web-sockets-with-go/problem"
+	"github.com/th3khan/rest-web-sockets-with-go/repositories"
 	"github.com/th3khan/rest-web-sockets-with-go/server"
 )
 
 var (
-	NO_AUTH_NEEDED = []string{
-		"login",
-		"signup",
-	}
+	publicRoutes = map[*mux.Route]bool{}
+	routeScopes  = map[*mux.Route]string{}
 )
 
-func shouldCheckToken(route string) bool {
-	for _, r := range NO_AUTH_NEEDED {
-		if strings.Contains(route, r) {
-			return false
-		}
+func Public(route *mux.Route) *mux.Route {
+	publicRoutes[route] = true
+	return route
+}
+
+func isPublic(r *http.Request) bool {
+	route := mux.CurrentRoute(r)
+	return route != nil && publicRoutes[route]
+}
+
+func Scoped(scope string, route *mux.Route) *mux.Route {
+	routeScopes[route] = scope
+	return route
+}
+
+func allowsAPIKey(r *http.Request, principal *auth.Principal) bool {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return false
 	}
-	return true
+	scope, ok := routeScopes[route]
+	return ok && principal.HasScope(scope)
+}
+
+func apiKeyPrincipal(r *http.Request, key string) (*auth.Principal, error) {
+	apiKey, err := repositories.GetAPIKeyByHash(r.Context(), auth.HashAPIKey(key))
+	if err != nil {
+		return nil, err
+	}
+	if apiKey == nil || apiKey.RevokedAt != nil {
+		return nil, problem.New(http.StatusUnauthorized, problem.CODE_INVALID_TOKEN, "invalid api key")
+	}
+	user, err := repositories.GetUserById(r.Context(), apiKey.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, problem.New(http.StatusUnauthorized, problem.CODE_INVALID_TOKEN, "invalid api key")
+	}
+	roles, err := repositories.GetUserRoles(r.Context(), user.ID)
+	if err != nil {
+		return nil, err
+	}
+	permissions, err := repositories.GetRolePermissions(r.Context(), roles)
+	if err != nil {
+		return nil, err
+	}
+	if err := repositories.TouchAPIKey(r.Context(), apiKey.ID); err != nil {
+		return nil, err
+	}
+	return &auth.Principal{
+		UserID:        user.ID,
+		Roles:         roles,
+		Permissions:   permissions,
+		EmailVerified: user.EmailVerified,
+		APIKeyID:      apiKey.ID,
+		Scopes:        apiKey.Scopes,
+	}, nil
 }
 
 func CheckAuthMiddleware(s server.Server) func(h http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			if !shouldCheckToken(r.URL.Path) {
+			if isPublic(r) {
 				next.ServeHTTP(w, r)
 				return
 			}
-			tokenString := strings.TrimSpace(r.Header.Get("Authorization"))
-			_, err := jwt.ParseWithClaims(tokenString, &models.AppClaims{}, func(token *jwt.Token) (interface{}, error) {
-				return []byte(s.Config().JWTSecret), nil
-			})
+			tokenString := strings.TrimSpace(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "))
+			if auth.IsAPIKey(tokenString) {
+				principal, err := apiKeyPrincipal(r, tokenString)
+				if err != nil {
+					problem.WriteError(w, r, err)
+					return
+				}
+				if !allowsAPIKey(r, principal) {
+					problem.Error(w, r, http.StatusForbidden, problem.CODE_INSUFFICIENT_SCOPE, "api key does not grant access to this route")
+					return
+				}
+				logging.RequestInfoFromContext(r.Context()).UserID = principal.UserID
+				next.ServeHTTP(w, r.WithContext(auth.WithPrincipal(r.Context(), principal)))
+				return
+			}
+			token, err := jwt.ParseWithClaims(tokenString, &models.AppClaims{}, s.Keys().Keyfunc)
 			if err != nil {
-				http.Error(w, err.Error(), http.StatusUnauthorized)
+				s.Logger().Debug("rejected access token", "error", err)
+				problem.Error(w, r, http.StatusUnauthorized, problem.CODE_INVALID_TOKEN, "invalid token")
+				return
+			}
+			claims, ok := token.Claims.(*models.AppClaims)
+			if !ok || !token.Valid || !claims.VerifyAudience(models.ACCESS_TOKEN_AUDIENCE, true) || claims.UserID == "" || claims.Id == "" {
+				problem.Error(w, r, http.StatusUnauthorized, problem.CODE_INVALID_TOKEN, "invalid token")
+				return
+			}
+			revoked, err := s.Revocations().IsRevoked(r.Context(), claims)
+			if err != nil {
+				problem.Internal(w, r, err)
+				return
+			}
+			if revoked {
+				problem.Error(w, r, http.StatusUnauthorized, problem.CODE_TOKEN_REVOKED, "token has been revoked")
+				return
+			}
+			logging.RequestInfoFromContext(r.Context()).UserID = claims.UserID
+			principal := &auth.Principal{
+				UserID:         claims.UserID,
+				Roles:          claims.Roles,
+				Permissions:    claims.Permissions,
+				EmailVerified:  claims.EmailVerified,
+				TokenID:        claims.Id,
+				TokenExpiresAt: time.Unix(claims.ExpiresAt, 0),
+				SessionID:      claims.SessionID,
+			}
+			if claims.SessionID != "" {
+				if err := repositories.TouchSession(r.Context(), claims.SessionID); err != nil {
+					s.Logger().Warn("could not update session", "session_id", claims.SessionID, "error", err)
+				}
+			}
+			next.ServeHTTP(w, r.WithContext(auth.WithPrincipal(r.Context(), principal)))
+		})
+	}
+}
+
+func RequirePermission(permission string) func(h http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, ok := auth.PrincipalFromContext(r.Context())
+			if !ok {
+				problem.Error(w, r, http.StatusUnauthorized, problem.CODE_UNAUTHORIZED, "authentication required")
+				return
+			}
+			if !principal.HasPermission(permission) {
+				problem.Error(w, r, http.StatusForbidden, problem.CODE_FORBIDDEN, "missing required permission "+permission)
 				return
 			}
 			next.ServeHTTP(w, r)
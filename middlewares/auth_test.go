@@ -0,0 +1,123 @@
+package middlewares
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/gorilla/mux"
+	"github.com/th3khan/rest-web-sockets-with-go/models"
+	"github.com/th3khan/rest-web-sockets-with-go/problem"
+	"github.com/th3khan/rest-web-sockets-with-go/repositories"
+	"github.com/th3khan/rest-web-sockets-with-go/server"
+)
+
+type fakeRepository struct {
+	repositories.Repository
+}
+
+func (f *fakeRepository) IsTokenRevoked(ctx context.Context, id string) (bool, error) {
+	return false, nil
+}
+
+func (f *fakeRepository) GetUserTokenVersion(ctx context.Context, userID string) (int, error) {
+	return 0, nil
+}
+
+func TestCheckAuthAcceptsOnlyAccessTokens(t *testing.T) {
+	repositories.SetRepository(&fakeRepository{})
+	t.Cleanup(func() { repositories.SetRepository(nil) })
+	s, err := server.NewServer(context.Background(), &server.Config{
+		Port:        ":0",
+		DataBaseUrl: "test",
+		JWTSecret:   "test-secret",
+		Environment: server.ENVIRONMENT_DEVELOPMENT,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := mux.NewRouter()
+	r.Use(CheckAuthMiddleware(s))
+	r.HandleFunc("/me", func(w http.ResponseWriter, r *http.Request) {}).Methods(http.MethodGet)
+
+	expiresAt := time.Now().Add(time.Minute).Unix()
+	tests := []struct {
+		name     string
+		claims   jwt.Claims
+		expected int
+	}{
+		{"access token", models.AppClaims{
+			UserID:         "user",
+			StandardClaims: jwt.StandardClaims{Id: "token", Audience: models.ACCESS_TOKEN_AUDIENCE, ExpiresAt: expiresAt},
+		}, http.StatusOK},
+		{"oidc link state", models.OIDCStateClaims{
+			State:          "state",
+			LinkUserID:     "user",
+			StandardClaims: jwt.StandardClaims{Audience: "oidc-state", ExpiresAt: expiresAt},
+		}, http.StatusUnauthorized},
+		{"state with user_id claim", jwt.MapClaims{
+			"user_id": "user",
+			"aud":     "oidc-state",
+			"jti":     "token",
+			"exp":     expiresAt,
+		}, http.StatusUnauthorized},
+		{"mfa challenge", jwt.StandardClaims{Subject: "user", Audience: "mfa", ExpiresAt: expiresAt}, http.StatusUnauthorized},
+		{"missing audience", models.AppClaims{
+			UserID:         "user",
+			StandardClaims: jwt.StandardClaims{Id: "token", ExpiresAt: expiresAt},
+		}, http.StatusUnauthorized},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			token, err := s.Keys().Sign(test.claims)
+			if err != nil {
+				t.Fatal(err)
+			}
+			recorder := httptest.NewRecorder()
+			request := httptest.NewRequest(http.MethodGet, "/me", nil)
+			request.Header.Set("Authorization", "Bearer "+token)
+			r.ServeHTTP(recorder, request)
+			if recorder.Code != test.expected {
+				t.Fatalf("expected %d, got %d", test.expected, recorder.Code)
+			}
+		})
+	}
+}
+
+func TestCheckAuthHidesParseErrors(t *testing.T) {
+	s, err := server.NewServer(context.Background(), &server.Config{
+		Port:        ":0",
+		DataBaseUrl: "test",
+		JWTSecret:   "test-secret",
+		Environment: server.ENVIRONMENT_DEVELOPMENT,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := mux.NewRouter()
+	r.Use(CheckAuthMiddleware(s))
+	r.HandleFunc("/me", func(w http.ResponseWriter, r *http.Request) {}).Methods(http.MethodGet)
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"user_id": "user"})
+	token.Header["kid"] = "unknown-key"
+	raw, err := token.SignedString([]byte("other-secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodGet, "/me", nil)
+	request.Header.Set("Authorization", "Bearer "+raw)
+	r.ServeHTTP(recorder, request)
+
+	var body problem.Problem
+	if err := json.NewDecoder(recorder.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if recorder.Code != http.StatusUnauthorized || body.Detail != "invalid token" {
+		t.Fatalf("expected a generic 401, got %d %q", recorder.Code, body.Detail)
+	}
+}
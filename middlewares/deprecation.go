@@ -0,0 +1,40 @@
+package middlewares
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/th3khan/rest-web-sockets-with-go/server"
+)
+
+var deprecatedRoutes = map[*mux.Route]string{}
+
+func Deprecated(successor string, router *mux.Router) error {
+	return router.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		if route.GetHandler() != nil {
+			deprecatedRoutes[route] = successor
+		}
+		return nil
+	})
+}
+
+func DeprecationMiddleware(s server.Server) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			route := mux.CurrentRoute(r)
+			if route != nil {
+				if successor, ok := deprecatedRoutes[route]; ok {
+					if deprecatedAt := s.Config().LegacyAPIDeprecatedAt; !deprecatedAt.IsZero() {
+						w.Header().Set("Deprecation", "@"+strconv.FormatInt(deprecatedAt.Unix(), 10))
+					}
+					if sunsetAt := s.Config().LegacyAPISunsetAt; !sunsetAt.IsZero() {
+						w.Header().Set("Sunset", sunsetAt.UTC().Format(http.TimeFormat))
+					}
+					w.Header().Add("Link", "<"+successor+r.URL.Path+`>; rel="successor-version"`)
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package middlewares
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/th3khan/rest-web-sockets-with-go/server"
+)
+
+func serveDeprecated(t *testing.T, config *server.Config) http.Header {
+	config.Port = ":0"
+	config.DataBaseUrl = "test"
+	config.JWTSecret = "test-secret"
+	config.Environment = server.ENVIRONMENT_DEVELOPMENT
+	s, err := server.NewServer(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := mux.NewRouter()
+	r.Use(DeprecationMiddleware(s))
+	legacy := r.NewRoute().Subrouter()
+	legacy.HandleFunc("/posts", func(w http.ResponseWriter, r *http.Request) {}).Methods(http.MethodGet)
+	if err := Deprecated("/v1", legacy); err != nil {
+		t.Fatal(err)
+	}
+	recorder := httptest.NewRecorder()
+	r.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/posts", nil))
+	return recorder.Header()
+}
+
+func TestDeprecationOmitsUnsetDates(t *testing.T) {
+	header := serveDeprecated(t, &server.Config{})
+	if header.Get("Deprecation") != "" || header.Get("Sunset") != "" {
+		t.Fatalf("expected no Deprecation or Sunset headers, got %q and %q", header.Get("Deprecation"), header.Get("Sunset"))
+	}
+	if header.Get("Link") != `</v1/posts>; rel="successor-version"` {
+		t.Fatalf("unexpected Link header %q", header.Get("Link"))
+	}
+}
+
+func TestDeprecationUsesConfiguredDates(t *testing.T) {
+	deprecatedAt := time.Date(2030, time.January, 1, 0, 0, 0, 0, time.UTC)
+	header := serveDeprecated(t, &server.Config{
+		LegacyAPIDeprecatedAt: deprecatedAt,
+		LegacyAPISunsetAt:     deprecatedAt.AddDate(0, 6, 0),
+	})
+	if header.Get("Deprecation") != "@1893456000" {
+		t.Fatalf("unexpected Deprecation header %q", header.Get("Deprecation"))
+	}
+	if header.Get("Sunset") != "Mon, 01 Jul 2030 00:00:00 GMT" {
+		t.Fatalf("unexpected Sunset header %q", header.Get("Sunset"))
+	}
+}
@@ -0,0 +1,136 @@
+package middlewares
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/th3khan/rest-web-sockets-with-go/auth"
+	"github.com/th3khan/rest-web-sockets-with-go/idempotency"
+	"github.com/th3khan/rest-web-sockets-with-go/problem"
+	"github.com/th3khan/rest-web-sockets-with-go/server"
+)
+
+const (
+	IDEMPOTENT_REPLAYED_HEADER = "Idempotent-Replayed"
+)
+
+type responseCapture struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+	header http.Header
+	before http.Header
+}
+
+func (c *responseCapture) WriteHeader(status int) {
+	if c.header == nil {
+		c.status = status
+		c.header = http.Header{}
+		for name, values := range c.ResponseWriter.Header() {
+			if _, ok := c.before[name]; !ok {
+				c.header[name] = append([]string(nil), values...)
+			}
+		}
+	}
+	c.ResponseWriter.WriteHeader(status)
+}
+
+func (c *responseCapture) Write(data []byte) (int, error) {
+	if c.header == nil {
+		c.WriteHeader(http.StatusOK)
+	}
+	c.body.Write(data)
+	return c.ResponseWriter.Write(data)
+}
+
+func mutating(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+func IdempotencyMiddleware(s server.Server) func(h http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(idempotency.HEADER)
+			principal, ok := auth.PrincipalFromContext(r.Context())
+			if key == "" || !ok || !mutating(r.Method) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if len(key) > idempotency.MAX_KEY_LENGTH {
+				problem.Error(w, r, http.StatusBadRequest, problem.CODE_BAD_REQUEST, "Idempotency-Key is too long")
+				return
+			}
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, idempotency.MAX_BODY_SIZE))
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				problem.Error(w, r, http.StatusRequestEntityTooLarge, problem.CODE_PAYLOAD_TOO_LARGE, "request body is too large")
+				return
+			}
+			if err != nil {
+				problem.MalformedBody(w, r, err)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			record, err := s.Idempotency().Begin(r.Context(), principal.UserID, key, idempotency.Fingerprint(r.Method, r.URL.Path, body))
+			if errors.Is(err, idempotency.ErrInProgress) {
+				problem.Error(w, r, http.StatusConflict, problem.CODE_CONFLICT, "a request with this Idempotency-Key is still being processed")
+				return
+			}
+			if errors.Is(err, idempotency.ErrMismatch) {
+				problem.Error(w, r, http.StatusUnprocessableEntity, problem.CODE_IDEMPOTENCY_REUSED, "Idempotency-Key was already used for a different request")
+				return
+			}
+			if err != nil {
+				problem.Internal(w, r, err)
+				return
+			}
+			if record != nil {
+				for name, values := range record.Header {
+					if _, ok := w.Header()[name]; !ok {
+						w.Header()[name] = values
+					}
+				}
+				w.Header().Set(IDEMPOTENT_REPLAYED_HEADER, "true")
+				w.WriteHeader(record.StatusCode)
+				w.Write(record.Body)
+				return
+			}
+
+			ctx := context.WithoutCancel(r.Context())
+			release := func() {
+				if err := s.Idempotency().Release(ctx, principal.UserID, key); err != nil {
+					s.Logger().Error("could not release idempotency key", "user_id", principal.UserID, "error", err)
+				}
+			}
+			capture := &responseCapture{
+				ResponseWriter: w,
+				status:         http.StatusOK,
+				before:         w.Header().Clone(),
+			}
+			func() {
+				defer func() {
+					if recovered := recover(); recovered != nil {
+						release()
+						panic(recovered)
+					}
+				}()
+				next.ServeHTTP(capture, r)
+			}()
+			if capture.header == nil || capture.status >= http.StatusInternalServerError {
+				release()
+				return
+			}
+			if err := s.Idempotency().Complete(ctx, principal.UserID, key, capture.status, capture.header, capture.body.Bytes()); err != nil {
+				s.Logger().Error("could not store idempotent response", "user_id", principal.UserID, "error", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package middlewares
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/th3khan/rest-web-sockets-with-go/auth"
+	"github.com/th3khan/rest-web-sockets-with-go/idempotency"
+	"github.com/th3khan/rest-web-sockets-with-go/server"
+)
+
+func newIdempotencyTestServer(t *testing.T) server.Server {
+	s, err := server.NewServer(context.Background(), &server.Config{
+		Port:        ":0",
+		DataBaseUrl: "test",
+		JWTSecret:   "test-secret",
+		Environment: server.ENVIRONMENT_DEVELOPMENT,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func idempotentRequest(body string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/posts", strings.NewReader(body))
+	r.Header.Set(idempotency.HEADER, "key")
+	return r.WithContext(auth.WithPrincipal(r.Context(), &auth.Principal{UserID: "user"}))
+}
+
+func TestIdempotencyReplaysHeaders(t *testing.T) {
+	s := newIdempotencyTestServer(t)
+	calls := 0
+	handler := IdempotencyMiddleware(s)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id":"post"}`))
+	}))
+	for i := 0; i < 2; i++ {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, idempotentRequest(`{"title":"a"}`))
+		if recorder.Code != http.StatusCreated || recorder.Header().Get("Content-Type") != "application/json" {
+			t.Fatalf("request %d: expected 201 application/json, got %d %q", i+1, recorder.Code, recorder.Header().Get("Content-Type"))
+		}
+	}
+	if calls != 1 {
+		t.Fatalf("expected the handler to run once, ran %d times", calls)
+	}
+}
+
+func TestIdempotencyReleasesKeyOnPanic(t *testing.T) {
+	s := newIdempotencyTestServer(t)
+	panicking := true
+	handler := IdempotencyMiddleware(s)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if panicking {
+			panic("boom")
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("expected the panic to propagate")
+			}
+		}()
+		handler.ServeHTTP(httptest.NewRecorder(), idempotentRequest(`{}`))
+	}()
+	panicking = false
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, idempotentRequest(`{}`))
+	if recorder.Code != http.StatusCreated {
+		t.Fatalf("expected the key to be released after a panic, got %d", recorder.Code)
+	}
+}
+
+func TestIdempotencyLimitsBodySize(t *testing.T) {
+	s := newIdempotencyTestServer(t)
+	handler := IdempotencyMiddleware(s)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Fatal("expected the handler not to run")
+	}))
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, idempotentRequest(strings.Repeat("a", idempotency.MAX_BODY_SIZE+1)))
+	if recorder.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected 413, got %d", recorder.Code)
+	}
+}
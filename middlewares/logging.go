@@ -0,0 +1,51 @@
+package middlewares
+
+import (
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/segmentio/ksuid"
+	"github.com/th3khan/rest-web-sockets-with-go/logging"
+	"github.com/th3khan/rest-web-sockets-with-go/server"
+)
+
+const (
+	REQUEST_ID_HEADER     = "X-Request-ID"
+	REQUEST_ID_MAX_LENGTH = 64
+)
+
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._:-]+$`)
+
+func validRequestID(id string) bool {
+	return len(id) <= REQUEST_ID_MAX_LENGTH && requestIDPattern.MatchString(id)
+}
+
+func LoggingMiddleware(s server.Server) func(h http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			requestID := r.Header.Get(REQUEST_ID_HEADER)
+			if !validRequestID(requestID) {
+				requestID = ksuid.New().String()
+			}
+			w.Header().Set(REQUEST_ID_HEADER, requestID)
+
+			info := &logging.RequestInfo{
+				ID: requestID,
+			}
+			recorder := newStatusRecorder(w)
+			next.ServeHTTP(recorder, r.WithContext(logging.WithRequestInfo(r.Context(), info)))
+
+			s.Logger().Info("request",
+				"request_id", info.ID,
+				"method", r.Method,
+				"route", routeTemplate(r),
+				"path", r.URL.Path,
+				"status", recorder.status,
+				"latency_ms", float64(time.Since(start).Microseconds())/1000,
+				"user_id", info.UserID,
+			)
+		})
+	}
+}
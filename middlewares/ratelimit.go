@@ -0,0 +1,95 @@
+package middlewares
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/th3khan/rest-web-sockets-with-go/auth"
+	"github.com/th3khan/rest-web-sockets-with-go/problem"
+	"github.com/th3khan/rest-web-sockets-with-go/ratelimit"
+	"github.com/th3khan/rest-web-sockets-with-go/server"
+)
+
+var versionless = regexp.MustCompile(`^/v[0-9]+(/|$)`)
+
+func routeKey(r *http.Request) string {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return r.Method + " " + r.URL.Path
+	}
+	template, err := route.GetPathTemplate()
+	if err != nil {
+		return r.Method + " " + r.URL.Path
+	}
+	return r.Method + " " + versionless.ReplaceAllString(template, "/")
+}
+
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func rateLimitIdentity(r *http.Request) string {
+	if principal, ok := auth.PrincipalFromContext(r.Context()); ok {
+		if principal.APIKeyID != "" {
+			return "key:" + principal.APIKeyID
+		}
+		return "user:" + principal.UserID
+	}
+	return "ip:" + remoteIP(r)
+}
+
+func seconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
+
+func enforceRateLimit(w http.ResponseWriter, r *http.Request, s server.Server, result *ratelimit.Result, err error) bool {
+	if err != nil {
+		s.Logger().Error("rate limiter unavailable", "error", err)
+		return true
+	}
+	if result == nil {
+		return true
+	}
+	w.Header().Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+	w.Header().Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	w.Header().Set("RateLimit-Reset", seconds(result.Reset))
+	if !result.Allowed {
+		w.Header().Set("Retry-After", seconds(result.RetryAfter))
+		problem.Error(w, r, http.StatusTooManyRequests, problem.CODE_RATE_LIMITED, "rate limit exceeded")
+		return false
+	}
+	return true
+}
+
+func IPRateLimitMiddleware(s server.Server) func(h http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			result, err := s.RateLimiter().AllowIP(r.Context(), remoteIP(r))
+			if !enforceRateLimit(w, r, s, result, err) {
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func RateLimitMiddleware(s server.Server) func(h http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			result, err := s.RateLimiter().Allow(r.Context(), routeKey(r), rateLimitIdentity(r))
+			if !enforceRateLimit(w, r, s, result, err) {
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
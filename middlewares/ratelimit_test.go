@@ -0,0 +1,39 @@
+package middlewares
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/th3khan/rest-web-sockets-with-go/ratelimit"
+	"github.com/th3khan/rest-web-sockets-with-go/server"
+)
+
+func TestIPRateLimitAppliesBeforeAuthentication(t *testing.T) {
+	s, err := server.NewServer(context.Background(), &server.Config{
+		Port:        ":0",
+		DataBaseUrl: "test",
+		JWTSecret:   "test-secret",
+		Environment: server.ENVIRONMENT_DEVELOPMENT,
+		RateLimit:   ratelimit.Config{IPLimit: "2/1m"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := mux.NewRouter()
+	r.Use(IPRateLimitMiddleware(s))
+	r.Use(CheckAuthMiddleware(s))
+	r.HandleFunc("/me", func(w http.ResponseWriter, r *http.Request) {}).Methods(http.MethodGet)
+
+	for i, expected := range []int{http.StatusUnauthorized, http.StatusUnauthorized, http.StatusTooManyRequests} {
+		recorder := httptest.NewRecorder()
+		request := httptest.NewRequest(http.MethodGet, "/me", nil)
+		request.Header.Set("Authorization", "Bearer invalid")
+		r.ServeHTTP(recorder, request)
+		if recorder.Code != expected {
+			t.Fatalf("request %d: expected %d, got %d", i+1, expected, recorder.Code)
+		}
+	}
+}
@@ -0,0 +1,9 @@
+package models
+
+import "time"
+
+type LoginAttempts struct {
+	Key           string    `json:"key"`
+	Failures      int       `json:"failures"`
+	LastFailureAt time.Time `json:"last_failure_at"`
+}
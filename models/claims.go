@@ -2,7 +2,22 @@ package models
 
 import "github.com/golang-jwt/jwt"
 
+const ACCESS_TOKEN_AUDIENCE = "access"
+
 type AppClaims struct {
-	UserID string `json:"user_id"`
+	UserID        string   `json:"user_id"`
+	Roles         []string `json:"roles,omitempty"`
+	Permissions   []string `json:"permissions,omitempty"`
+	EmailVerified bool     `json:"email_verified"`
+	TokenVersion  int      `json:"ver"`
+	SessionID     string   `json:"sid,omitempty"`
+	jwt.StandardClaims
+}
+
+type OIDCStateClaims struct {
+	State      string `json:"state"`
+	Nonce      string `json:"nonce"`
+	Verifier   string `json:"verifier"`
+	LinkUserID string `json:"link_user,omitempty"`
 	jwt.StandardClaims
 }
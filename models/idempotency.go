@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+type IdempotencyRecord struct {
+	UserID      string              `json:"user_id"`
+	Key         string              `json:"key"`
+	Fingerprint string              `json:"fingerprint"`
+	StatusCode  int                 `json:"status_code"`
+	Header      map[string][]string `json:"header"`
+	Body        []byte              `json:"body"`
+	CompletedAt *time.Time          `json:"completed_at"`
+	ExpiresAt   time.Time           `json:"expires_at"`
+}
@@ -0,0 +1,10 @@
+package models
+
+import "time"
+
+type TOTP struct {
+	UserID       string     `json:"user_id"`
+	Secret       string     `json:"-"`
+	ConfirmedAt  *time.Time `json:"confirmed_at"`
+	LastUsedStep int64      `json:"-"`
+}
@@ -0,0 +1,14 @@
+package models
+
+const (
+	ROLE_USER      = "user"
+	ROLE_MODERATOR = "moderator"
+	ROLE_ADMIN     = "admin"
+
+	PERMISSION_POSTS_EDIT_ANY   = "posts:edit_any"
+	PERMISSION_POSTS_DELETE_ANY = "posts:delete_any"
+	PERMISSION_USERS_MANAGE     = "users:manage"
+
+	SCOPE_POSTS_READ  = "posts:read"
+	SCOPE_POSTS_WRITE = "posts:write"
+)
@@ -1,7 +1,10 @@
 package models
 
 type User struct {
-	ID       string `json:"id"`
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	ID            string   `json:"id"`
+	Email         string   `json:"email"`
+	Password      string   `json:"-"`
+	EmailVerified bool     `json:"email_verified"`
+	TokenVersion  int      `json:"-"`
+	Roles         []string `json:"roles,omitempty"`
 }
@@ -0,0 +1,234 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+const (
+	TEST_CLIENT_ID = "client"
+	TEST_KEY_ID    = "test-key"
+)
+
+type mockIssuer struct {
+	*httptest.Server
+	key *rsa.PrivateKey
+
+	mu         sync.Mutex
+	challenges map[string]string
+	idToken    string
+}
+
+func newMockIssuer(t *testing.T) *mockIssuer {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuer := &mockIssuer{key: key, challenges: map[string]string{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc(DISCOVERY_PATH, func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(Discovery{
+			Issuer:                issuer.URL,
+			AuthorizationEndpoint: issuer.URL + "/authorize",
+			TokenEndpoint:         issuer.URL + "/token",
+			JWKSURI:               issuer.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string][]jsonWebKey{
+			"keys": {{
+				KeyType: "RSA",
+				KeyID:   TEST_KEY_ID,
+				Use:     "sig",
+				N:       base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				E:       base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil || r.PostForm.Get("grant_type") != "authorization_code" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		issuer.mu.Lock()
+		challenge, ok := issuer.challenges[r.PostForm.Get("code")]
+		idToken := issuer.idToken
+		issuer.mu.Unlock()
+		if !ok || CodeChallenge(r.PostForm.Get("code_verifier")) != challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(TokenResponse{AccessToken: "access", TokenType: "Bearer", IDToken: idToken})
+	})
+	issuer.Server = httptest.NewServer(mux)
+	t.Cleanup(issuer.Close)
+	return issuer
+}
+
+func (m *mockIssuer) authorize(code string, challenge string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.challenges[code] = challenge
+}
+
+func (m *mockIssuer) sign(t *testing.T, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = TEST_KEY_ID
+	raw, err := token.SignedString(m.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+func (m *mockIssuer) claims(nonce string) jwt.MapClaims {
+	return jwt.MapClaims{
+		"iss":            m.URL,
+		"aud":            TEST_CLIENT_ID,
+		"sub":            "subject",
+		"email":          "user@example.com",
+		"email_verified": true,
+		"nonce":          nonce,
+		"exp":            time.Now().Add(time.Minute).Unix(),
+	}
+}
+
+func newTestProvider(t *testing.T, issuer *mockIssuer) *Provider {
+	provider, err := NewProvider(&Config{
+		IssuerURL:   issuer.URL,
+		ClientID:    TEST_CLIENT_ID,
+		RedirectURL: "http://localhost/callback",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return provider
+}
+
+func TestDiscover(t *testing.T) {
+	issuer := newMockIssuer(t)
+	discovery, err := newTestProvider(t, issuer).Discover(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if discovery.TokenEndpoint != issuer.URL+"/token" {
+		t.Fatalf("unexpected token endpoint %s", discovery.TokenEndpoint)
+	}
+}
+
+func TestDiscoverRejectsIssuerMismatch(t *testing.T) {
+	issuer := newMockIssuer(t)
+	provider, err := NewProvider(&Config{
+		IssuerURL:   issuer.URL + "/tenant",
+		ClientID:    TEST_CLIENT_ID,
+		RedirectURL: "http://localhost/callback",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := provider.Discover(context.Background()); err == nil {
+		t.Fatal("expected discovery for another issuer to fail")
+	}
+}
+
+func TestExchangeUsesPKCE(t *testing.T) {
+	issuer := newMockIssuer(t)
+	provider := newTestProvider(t, issuer)
+	verifier, err := NewCodeVerifier()
+	if err != nil {
+		t.Fatal(err)
+	}
+	location, err := provider.AuthCodeURL(context.Background(), "state", "nonce", verifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+	redirect, err := url.Parse(location)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := redirect.Query()
+	if query.Get("code_challenge_method") != PKCE_METHOD || query.Get("code_challenge") != CodeChallenge(verifier) {
+		t.Fatalf("unexpected pkce parameters in %s", location)
+	}
+	if query.Get("state") != "state" || query.Get("nonce") != "nonce" || query.Get("client_id") != TEST_CLIENT_ID {
+		t.Fatalf("unexpected authorization parameters in %s", location)
+	}
+	issuer.authorize("code", query.Get("code_challenge"))
+	issuer.idToken = issuer.sign(t, issuer.claims("nonce"))
+
+	if _, err := provider.Exchange(context.Background(), "code", "wrong-verifier"); err == nil {
+		t.Fatal("expected exchange with the wrong verifier to fail")
+	}
+	token, err := provider.Exchange(context.Background(), "code", verifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := provider.VerifyIDToken(context.Background(), token.IDToken, "nonce")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Subject != "subject" || claims.Email != "user@example.com" || !claims.EmailVerified {
+		t.Fatalf("unexpected claims %+v", claims)
+	}
+}
+
+func TestVerifyIDTokenRejectsInvalidClaims(t *testing.T) {
+	issuer := newMockIssuer(t)
+	provider := newTestProvider(t, issuer)
+	tests := []struct {
+		name   string
+		modify func(claims jwt.MapClaims)
+	}{
+		{"nonce", func(claims jwt.MapClaims) { claims["nonce"] = "other" }},
+		{"issuer", func(claims jwt.MapClaims) { claims["iss"] = "https://attacker.example.com" }},
+		{"audience", func(claims jwt.MapClaims) { claims["aud"] = "other-client" }},
+		{"audience list", func(claims jwt.MapClaims) { claims["aud"] = []string{"other-client", "another"} }},
+		{"subject", func(claims jwt.MapClaims) { delete(claims, "sub") }},
+		{"expiry", func(claims jwt.MapClaims) { claims["exp"] = time.Now().Add(-time.Minute).Unix() }},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			claims := issuer.claims("nonce")
+			test.modify(claims)
+			if _, err := provider.VerifyIDToken(context.Background(), issuer.sign(t, claims), "nonce"); err == nil {
+				t.Fatal("expected id token to be rejected")
+			}
+		})
+	}
+}
+
+func TestVerifyIDTokenAcceptsAudienceList(t *testing.T) {
+	issuer := newMockIssuer(t)
+	provider := newTestProvider(t, issuer)
+	claims := issuer.claims("nonce")
+	claims["aud"] = []string{"other-client", TEST_CLIENT_ID}
+	if _, err := provider.VerifyIDToken(context.Background(), issuer.sign(t, claims), "nonce"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestVerifyIDTokenRejectsUnknownKey(t *testing.T) {
+	issuer := newMockIssuer(t)
+	provider := newTestProvider(t, issuer)
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, issuer.claims("nonce"))
+	token.Header["kid"] = "other-key"
+	raw, err := token.SignedString(issuer.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := provider.VerifyIDToken(context.Background(), raw, "nonce"); err == nil {
+		t.Fatal("expected id token signed with an unknown key to be rejected")
+	}
+}
@@ -0,0 +1,226 @@
+package passwords
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	ALGORITHM_BCRYPT   = "bcrypt"
+	ALGORITHM_ARGON2ID = "argon2id"
+)
+
+var (
+	ErrInvalidHash = errors.New("invalid password hash")
+)
+
+type Config struct {
+	MinLength        int
+	RequireMixedCase bool
+	RequireDigit     bool
+	RequireSymbol    bool
+	BreachedListFile string
+	Algorithm        string
+	BcryptCost       int
+	Argon2Time       uint32
+	Argon2Memory     uint32
+	Argon2Threads    uint8
+	Argon2KeyLen     uint32
+	Argon2SaltLen    uint32
+}
+
+type Policy struct {
+	config   *Config
+	breached map[string]bool
+}
+
+func NewPolicy(config *Config) (*Policy, error) {
+	if config.Algorithm == "" {
+		config.Algorithm = ALGORITHM_BCRYPT
+	}
+	if config.BcryptCost == 0 {
+		config.BcryptCost = bcrypt.DefaultCost
+	}
+	if config.Argon2Time == 0 {
+		config.Argon2Time = 1
+	}
+	if config.Argon2Memory == 0 {
+		config.Argon2Memory = 64 * 1024
+	}
+	if config.Argon2Threads == 0 {
+		config.Argon2Threads = 4
+	}
+	if config.Argon2KeyLen == 0 {
+		config.Argon2KeyLen = 32
+	}
+	if config.Argon2SaltLen == 0 {
+		config.Argon2SaltLen = 16
+	}
+	switch config.Algorithm {
+	case ALGORITHM_BCRYPT:
+		if config.BcryptCost < bcrypt.MinCost || config.BcryptCost > bcrypt.MaxCost {
+			return nil, fmt.Errorf("bcrypt cost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+		}
+	case ALGORITHM_ARGON2ID:
+	default:
+		return nil, fmt.Errorf("unsupported password hashing algorithm %s", config.Algorithm)
+	}
+	if config.MinLength == 0 {
+		config.MinLength = 8
+	}
+	breached, err := loadBreachedList(config.BreachedListFile)
+	if err != nil {
+		return nil, err
+	}
+	return &Policy{
+		config:   config,
+		breached: breached,
+	}, nil
+}
+
+func loadBreachedList(path string) (map[string]bool, error) {
+	breached := make(map[string]bool)
+	if path == "" {
+		return breached, nil
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			breached[line] = true
+		}
+	}
+	return breached, scanner.Err()
+}
+
+func (p *Policy) Validate(password string) []string {
+	var problems []string
+	if utf8.RuneCountInString(password) < p.config.MinLength {
+		problems = append(problems, fmt.Sprintf("must be at least %d characters", p.config.MinLength))
+	}
+	var lower, upper, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+	if p.config.RequireMixedCase && !(lower && upper) {
+		problems = append(problems, "must contain upper and lower case letters")
+	}
+	if p.config.RequireDigit && !digit {
+		problems = append(problems, "must contain a digit")
+	}
+	if p.config.RequireSymbol && !symbol {
+		problems = append(problems, "must contain a symbol")
+	}
+	if p.breached[password] {
+		problems = append(problems, "appears in a list of breached passwords")
+	}
+	return problems
+}
+
+func (p *Policy) Hash(password string) (string, error) {
+	if p.config.Algorithm == ALGORITHM_ARGON2ID {
+		salt := make([]byte, p.config.Argon2SaltLen)
+		if _, err := rand.Read(salt); err != nil {
+			return "", err
+		}
+		key := argon2.IDKey([]byte(password), salt, p.config.Argon2Time, p.config.Argon2Memory, p.config.Argon2Threads, p.config.Argon2KeyLen)
+		return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+			argon2.Version, p.config.Argon2Memory, p.config.Argon2Time, p.config.Argon2Threads,
+			base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), p.config.BcryptCost)
+	return string(hash), err
+}
+
+func (p *Policy) Verify(hash string, password string) (bool, error) {
+	if strings.HasPrefix(hash, "$argon2id$") {
+		params, salt, key, err := decodeArgon2id(hash)
+		if err != nil {
+			return false, err
+		}
+		other := argon2.IDKey([]byte(password), salt, params.time, params.memory, params.threads, uint32(len(key)))
+		return subtle.ConstantTimeCompare(key, other) == 1, nil
+	}
+	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	if err == bcrypt.ErrMismatchedHashAndPassword {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (p *Policy) NeedsRehash(hash string) bool {
+	if strings.HasPrefix(hash, "$argon2id$") {
+		if p.config.Algorithm != ALGORITHM_ARGON2ID {
+			return true
+		}
+		params, salt, key, err := decodeArgon2id(hash)
+		if err != nil {
+			return true
+		}
+		return params.version != argon2.Version ||
+			params.time != p.config.Argon2Time ||
+			params.memory != p.config.Argon2Memory ||
+			params.threads != p.config.Argon2Threads ||
+			uint32(len(salt)) != p.config.Argon2SaltLen ||
+			uint32(len(key)) != p.config.Argon2KeyLen
+	}
+	if p.config.Algorithm != ALGORITHM_BCRYPT {
+		return true
+	}
+	cost, err := bcrypt.Cost([]byte(hash))
+	return err != nil || cost != p.config.BcryptCost
+}
+
+type argon2Params struct {
+	version int
+	memory  uint32
+	time    uint32
+	threads uint8
+}
+
+func decodeArgon2id(hash string) (*argon2Params, []byte, []byte, error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 {
+		return nil, nil, nil, ErrInvalidHash
+	}
+	var params argon2Params
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &params.version); err != nil {
+		return nil, nil, nil, ErrInvalidHash
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.memory, &params.time, &params.threads); err != nil {
+		return nil, nil, nil, ErrInvalidHash
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return nil, nil, nil, ErrInvalidHash
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return nil, nil, nil, ErrInvalidHash
+	}
+	return &params, salt, key, nil
+}
@@ -0,0 +1,128 @@
+package problem
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/th3khan/rest-web-sockets-with-go/logging"
+)
+
+const (
+	CONTENT_TYPE = "application/problem+json"
+
+	CODE_BAD_REQUEST         = "bad_request"
+	CODE_MALFORMED_BODY      = "malformed_body"
+	CODE_PAYLOAD_TOO_LARGE   = "payload_too_large"
+	CODE_VALIDATION_FAILED   = "validation_failed"
+	CODE_UNAUTHORIZED        = "unauthorized"
+	CODE_INVALID_CREDENTIALS = "invalid_credentials"
+	CODE_INVALID_TOKEN       = "invalid_token"
+	CODE_TOKEN_REVOKED       = "token_revoked"
+	CODE_INVALID_CODE        = "invalid_code"
+	CODE_FORBIDDEN           = "forbidden"
+	CODE_EMAIL_NOT_VERIFIED  = "email_not_verified"
+	CODE_INSUFFICIENT_SCOPE  = "insufficient_scope"
+	CODE_NOT_FOUND           = "not_found"
+	CODE_METHOD_NOT_ALLOWED  = "method_not_allowed"
+	CODE_CONFLICT            = "conflict"
+	CODE_IDEMPOTENCY_REUSED  = "idempotency_key_reused"
+	CODE_ACCOUNT_LOCKED      = "account_locked"
+	CODE_RATE_LIMITED        = "rate_limited"
+	CODE_UPSTREAM_FAILED     = "upstream_failed"
+	CODE_INTERNAL            = "internal_error"
+)
+
+var exposeInternal atomic.Bool
+
+type Problem struct {
+	Type      string            `json:"type"`
+	Title     string            `json:"title"`
+	Status    int               `json:"status"`
+	Code      string            `json:"code"`
+	Detail    string            `json:"detail,omitempty"`
+	Instance  string            `json:"instance,omitempty"`
+	RequestID string            `json:"request_id,omitempty"`
+	Errors    map[string]string `json:"errors,omitempty"`
+}
+
+func New(status int, code string, detail string) *Problem {
+	return &Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Code:   code,
+		Detail: detail,
+	}
+}
+
+func (p *Problem) Error() string {
+	return p.Detail
+}
+
+func (p *Problem) WithErrors(errs map[string]string) *Problem {
+	p.Errors = errs
+	return p
+}
+
+func SetExposeInternal(expose bool) {
+	exposeInternal.Store(expose)
+}
+
+func ExposesInternal() bool {
+	return exposeInternal.Load()
+}
+
+func Write(w http.ResponseWriter, r *http.Request, p *Problem) {
+	p.Instance = r.URL.Path
+	p.RequestID = logging.RequestInfoFromContext(r.Context()).ID
+	w.Header().Set("Content-Type", CONTENT_TYPE)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(p.Status)
+	json.NewEncoder(w).Encode(p)
+}
+
+func Error(w http.ResponseWriter, r *http.Request, status int, code string, detail string) {
+	Write(w, r, New(status, code, detail))
+}
+
+func Internal(w http.ResponseWriter, r *http.Request, err error) {
+	info := logging.RequestInfoFromContext(r.Context())
+	slog.ErrorContext(r.Context(), "internal error", "request_id", info.ID, "method", r.Method, "path", r.URL.Path, "error", err)
+	detail := "an unexpected error occurred"
+	if exposeInternal.Load() && err != nil {
+		detail = err.Error()
+	}
+	Error(w, r, http.StatusInternalServerError, CODE_INTERNAL, detail)
+}
+
+func MalformedBody(w http.ResponseWriter, r *http.Request, err error) {
+	Error(w, r, http.StatusBadRequest, CODE_MALFORMED_BODY, "request body is not valid JSON: "+err.Error())
+}
+
+func Validation(w http.ResponseWriter, r *http.Request, status int, errs map[string]string) {
+	Write(w, r, New(status, CODE_VALIDATION_FAILED, "validation failed").WithErrors(errs))
+}
+
+func WriteError(w http.ResponseWriter, r *http.Request, err error) {
+	var p *Problem
+	if errors.As(err, &p) {
+		Write(w, r, p)
+		return
+	}
+	Internal(w, r, err)
+}
+
+func NotFoundHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Error(w, r, http.StatusNotFound, CODE_NOT_FOUND, "no route matches "+r.URL.Path)
+	})
+}
+
+func MethodNotAllowedHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Error(w, r, http.StatusMethodNotAllowed, CODE_METHOD_NOT_ALLOWED, r.Method+" is not allowed on "+r.URL.Path)
+	})
+}
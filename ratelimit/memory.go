@@ -0,0 +1,66 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	MEMORY_PRUNE_INTERVAL = time.Minute
+)
+
+type bucket struct {
+	tokens    float64
+	capacity  float64
+	rate      float64
+	updatedAt time.Time
+}
+
+type MemoryStore struct {
+	mutex   *sync.Mutex
+	buckets map[string]*bucket
+	pruned  time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		mutex:   &sync.Mutex{},
+		buckets: make(map[string]*bucket),
+		pruned:  time.Now(),
+	}
+}
+
+func (s *MemoryStore) prune(now time.Time) {
+	if now.Sub(s.pruned) < MEMORY_PRUNE_INTERVAL {
+		return
+	}
+	s.pruned = now
+	for key, b := range s.buckets {
+		if b.tokens+now.Sub(b.updatedAt).Seconds()*b.rate >= b.capacity {
+			delete(s.buckets, key)
+		}
+	}
+}
+
+func (s *MemoryStore) Take(ctx context.Context, key string, capacity float64, rate float64) (float64, bool, error) {
+	now := time.Now()
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.prune(now)
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: capacity, updatedAt: now}
+		s.buckets[key] = b
+	}
+	b.capacity = capacity
+	b.rate = rate
+	b.tokens = math.Min(capacity, b.tokens+now.Sub(b.updatedAt).Seconds()*rate)
+	b.updatedAt = now
+	if b.tokens < 1 {
+		return b.tokens, false, nil
+	}
+	b.tokens--
+	return b.tokens, true, nil
+}
@@ -0,0 +1,162 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	DRIVER_MEMORY   = "memory"
+	DRIVER_DATABASE = "database"
+
+	DEFAULT_IP_LIMIT = "600/1m"
+)
+
+type Store interface {
+	Take(ctx context.Context, key string, capacity float64, rate float64) (float64, bool, error)
+}
+
+type Config struct {
+	Driver       string
+	DefaultLimit string
+	RouteLimits  string
+	IPLimit      string
+}
+
+type Limit struct {
+	Requests int
+	Period   time.Duration
+}
+
+func (l Limit) rate() float64 {
+	return float64(l.Requests) / l.Period.Seconds()
+}
+
+type Result struct {
+	Limit      int
+	Remaining  int
+	Reset      time.Duration
+	RetryAfter time.Duration
+	Allowed    bool
+}
+
+type Limiter struct {
+	store    Store
+	fallback *Limit
+	routes   map[string]Limit
+	ip       Limit
+}
+
+func ParseLimit(value string) (Limit, error) {
+	parts := strings.SplitN(strings.TrimSpace(value), "/", 2)
+	if len(parts) != 2 {
+		return Limit{}, fmt.Errorf("invalid rate limit %q, expected requests/period", value)
+	}
+	requests, err := strconv.Atoi(parts[0])
+	if err != nil || requests <= 0 {
+		return Limit{}, fmt.Errorf("invalid request count in rate limit %q", value)
+	}
+	period, err := time.ParseDuration(parts[1])
+	if err != nil || period <= 0 {
+		return Limit{}, fmt.Errorf("invalid period in rate limit %q", value)
+	}
+	return Limit{Requests: requests, Period: period}, nil
+}
+
+func ParseRoutes(value string) (map[string]Limit, error) {
+	routes := map[string]Limit{}
+	for _, entry := range strings.Split(value, ",") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid route rate limit %q, expected METHOD /path=requests/period", entry)
+		}
+		limit, err := ParseLimit(parts[1])
+		if err != nil {
+			return nil, err
+		}
+		routes[strings.Join(strings.Fields(parts[0]), " ")] = limit
+	}
+	return routes, nil
+}
+
+func New(config *Config) (*Limiter, error) {
+	var store Store
+	switch config.Driver {
+	case "", DRIVER_MEMORY:
+		store = NewMemoryStore()
+	case DRIVER_DATABASE:
+		store = NewRepositoryStore()
+	default:
+		return nil, fmt.Errorf("unknown rate limit driver %s", config.Driver)
+	}
+	limiter := &Limiter{
+		store: store,
+	}
+	if config.DefaultLimit != "" {
+		limit, err := ParseLimit(config.DefaultLimit)
+		if err != nil {
+			return nil, err
+		}
+		limiter.fallback = &limit
+	}
+	routes, err := ParseRoutes(config.RouteLimits)
+	if err != nil {
+		return nil, err
+	}
+	limiter.routes = routes
+	if config.IPLimit == "" {
+		config.IPLimit = DEFAULT_IP_LIMIT
+	}
+	limiter.ip, err = ParseLimit(config.IPLimit)
+	if err != nil {
+		return nil, err
+	}
+	return limiter, nil
+}
+
+func (l *Limiter) limitFor(route string) (Limit, bool) {
+	if limit, ok := l.routes[route]; ok {
+		return limit, true
+	}
+	if l.fallback != nil {
+		return *l.fallback, true
+	}
+	return Limit{}, false
+}
+
+func (l *Limiter) take(ctx context.Context, limit Limit, key string) (*Result, error) {
+	capacity := float64(limit.Requests)
+	tokens, allowed, err := l.store.Take(ctx, key, capacity, limit.rate())
+	if err != nil {
+		return nil, err
+	}
+	result := &Result{
+		Limit:     limit.Requests,
+		Remaining: int(math.Floor(tokens)),
+		Reset:     time.Duration((capacity - tokens) / limit.rate() * float64(time.Second)),
+		Allowed:   allowed,
+	}
+	if !allowed {
+		result.RetryAfter = time.Duration((1 - tokens) / limit.rate() * float64(time.Second))
+	}
+	return result, nil
+}
+
+func (l *Limiter) Allow(ctx context.Context, route string, identity string) (*Result, error) {
+	limit, ok := l.limitFor(route)
+	if !ok {
+		return nil, nil
+	}
+	return l.take(ctx, limit, route+"|"+identity)
+}
+
+func (l *Limiter) AllowIP(ctx context.Context, ip string) (*Result, error) {
+	return l.take(ctx, l.ip, "ip|"+ip)
+}
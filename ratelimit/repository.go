@@ -0,0 +1,17 @@
+package ratelimit
+
+import (
+	"context"
+
+	"github.com/th3khan/rest-web-sockets-with-go/repositories"
+)
+
+type RepositoryStore struct{}
+
+func NewRepositoryStore() *RepositoryStore {
+	return &RepositoryStore{}
+}
+
+func (s *RepositoryStore) Take(ctx context.Context, key string, capacity float64, rate float64) (float64, bool, error) {
+	return repositories.TakeRateLimitToken(ctx, key, capacity, rate)
+}
@@ -2,6 +2,8 @@ package repositories
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"github.com/th3khan/rest-web-sockets-with-go/models"
 )
@@ -10,14 +12,71 @@ type Repository interface {
 	InsertUser(ctx context.Context, user *models.User) error
 	GetUserById(ctx context.Context, id string) (*models.User, error)
 	GetUserByEmail(ctx context.Context, email string) (*models.User, error)
+	MarkEmailVerified(ctx context.Context, userID string) error
+	UpdateUserPassword(ctx context.Context, userID string, password string) error
 	InsertPost(ctx context.Context, post *models.Post) error
 	GetPostById(ctx context.Context, id string) (*models.Post, error)
 	UpdatePost(ctx context.Context, post *models.Post) error
 	DeletePost(ctx context.Context, id string, userId string) error
 	ListPosts(ctx context.Context, page uint64) ([]*models.Post, error)
+	InsertRefreshToken(ctx context.Context, token *models.RefreshToken) error
+	GetRefreshTokenByHash(ctx context.Context, hash string) (*models.RefreshToken, error)
+	RevokeRefreshToken(ctx context.Context, id string, replacedBy string) (bool, error)
+	RevokeRefreshTokenFamily(ctx context.Context, familyID string) error
+	InsertRevokedToken(ctx context.Context, token *models.RevokedToken) error
+	IsTokenRevoked(ctx context.Context, id string) (bool, error)
+	RevokeUserTokens(ctx context.Context, userID string) error
+	GetUserTokenVersion(ctx context.Context, userID string) (int, error)
+	InsertPasswordResetToken(ctx context.Context, token *models.PasswordResetToken) error
+	GetPasswordResetTokenByHash(ctx context.Context, hash string) (*models.PasswordResetToken, error)
+	MarkPasswordResetTokenUsed(ctx context.Context, id string) (bool, error)
+	InvalidatePasswordResetTokens(ctx context.Context, userID string) error
+	InsertEmailVerificationToken(ctx context.Context, token *models.EmailVerificationToken) error
+	GetEmailVerificationTokenByHash(ctx context.Context, hash string) (*models.EmailVerificationToken, error)
+	MarkEmailVerificationTokenUsed(ctx context.Context, id string) (bool, error)
+	GetTOTP(ctx context.Context, userID string) (*models.TOTP, error)
+	UpsertTOTP(ctx context.Context, totp *models.TOTP) error
+	ConfirmTOTP(ctx context.Context, userID string) error
+	DeleteTOTP(ctx context.Context, userID string) error
+	UseTOTPStep(ctx context.Context, userID string, step int64) (bool, error)
+	ReplaceRecoveryCodes(ctx context.Context, userID string, hashes []string) error
+	UseRecoveryCode(ctx context.Context, userID string, hash string) (bool, error)
+	InsertExternalIdentity(ctx context.Context, identity *models.ExternalIdentity) error
+	GetExternalIdentity(ctx context.Context, provider string, subject string) (*models.ExternalIdentity, error)
+	InsertAPIKey(ctx context.Context, key *models.APIKey) error
+	GetAPIKeyByHash(ctx context.Context, hash string) (*models.APIKey, error)
+	ListAPIKeys(ctx context.Context, userID string) ([]*models.APIKey, error)
+	RevokeAPIKey(ctx context.Context, id string, userID string) (bool, error)
+	TouchAPIKey(ctx context.Context, id string) error
+	GetLoginAttempts(ctx context.Context, key string) (*models.LoginAttempts, error)
+	RecordLoginFailure(ctx context.Context, key string, window time.Duration) (*models.LoginAttempts, error)
+	ResetLoginAttempts(ctx context.Context, key string) error
+	ReleaseLoginAttempt(ctx context.Context, key string) error
+	InsertSession(ctx context.Context, session *models.Session) error
+	RefreshSession(ctx context.Context, id string, ip string, expiresAt time.Time) error
+	TouchSession(ctx context.Context, id string) error
+	ListSessions(ctx context.Context, userID string) ([]*models.Session, error)
+	RevokeSession(ctx context.Context, id string, userID string) (bool, error)
+	IsSessionRevoked(ctx context.Context, id string) (bool, error)
+	TakeRateLimitToken(ctx context.Context, key string, capacity float64, rate float64) (float64, bool, error)
+	ReserveIdempotencyKey(ctx context.Context, record *models.IdempotencyRecord) (bool, error)
+	GetIdempotencyRecord(ctx context.Context, userID string, key string) (*models.IdempotencyRecord, error)
+	CompleteIdempotencyRecord(ctx context.Context, record *models.IdempotencyRecord) error
+	DeleteIdempotencyRecord(ctx context.Context, userID string, key string) error
+	ListUsers(ctx context.Context, page uint64) ([]*models.User, error)
+	GetUserRoles(ctx context.Context, userID string) ([]string, error)
+	GetRolePermissions(ctx context.Context, roles []string) ([]string, error)
+	SetUserRoles(ctx context.Context, userID string, roles []string) error
+	Ping(ctx context.Context) error
+	SchemaVersion(ctx context.Context) (int, error)
 	Close() error
 }
 
+var (
+	ErrDuplicate = errors.New("duplicate entry")
+	ErrNotFound  = errors.New("not found")
+)
+
 var implementation Repository
 
 func SetRepository(repository Repository) {
@@ -36,6 +95,14 @@ func GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
 	return implementation.GetUserByEmail(ctx, email)
 }
 
+func MarkEmailVerified(ctx context.Context, userID string) error {
+	return implementation.MarkEmailVerified(ctx, userID)
+}
+
+func UpdateUserPassword(ctx context.Context, userID string, password string) error {
+	return implementation.UpdateUserPassword(ctx, userID, password)
+}
+
 func InsertPost(ctx context.Context, post *models.Post) error {
 	return implementation.InsertPost(ctx, post)
 }
@@ -56,6 +123,206 @@ func ListPosts(ctx context.Context, page uint64) ([]*models.Post, error) {
 	return implementation.ListPosts(ctx, page)
 }
 
+func InsertRefreshToken(ctx context.Context, token *models.RefreshToken) error {
+	return implementation.InsertRefreshToken(ctx, token)
+}
+
+func GetRefreshTokenByHash(ctx context.Context, hash string) (*models.RefreshToken, error) {
+	return implementation.GetRefreshTokenByHash(ctx, hash)
+}
+
+func RevokeRefreshToken(ctx context.Context, id string, replacedBy string) (bool, error) {
+	return implementation.RevokeRefreshToken(ctx, id, replacedBy)
+}
+
+func RevokeRefreshTokenFamily(ctx context.Context, familyID string) error {
+	return implementation.RevokeRefreshTokenFamily(ctx, familyID)
+}
+
+func InsertRevokedToken(ctx context.Context, token *models.RevokedToken) error {
+	return implementation.InsertRevokedToken(ctx, token)
+}
+
+func IsTokenRevoked(ctx context.Context, id string) (bool, error) {
+	return implementation.IsTokenRevoked(ctx, id)
+}
+
+func RevokeUserTokens(ctx context.Context, userID string) error {
+	return implementation.RevokeUserTokens(ctx, userID)
+}
+
+func GetUserTokenVersion(ctx context.Context, userID string) (int, error) {
+	return implementation.GetUserTokenVersion(ctx, userID)
+}
+
+func InsertPasswordResetToken(ctx context.Context, token *models.PasswordResetToken) error {
+	return implementation.InsertPasswordResetToken(ctx, token)
+}
+
+func GetPasswordResetTokenByHash(ctx context.Context, hash string) (*models.PasswordResetToken, error) {
+	return implementation.GetPasswordResetTokenByHash(ctx, hash)
+}
+
+func MarkPasswordResetTokenUsed(ctx context.Context, id string) (bool, error) {
+	return implementation.MarkPasswordResetTokenUsed(ctx, id)
+}
+
+func InvalidatePasswordResetTokens(ctx context.Context, userID string) error {
+	return implementation.InvalidatePasswordResetTokens(ctx, userID)
+}
+
+func InsertEmailVerificationToken(ctx context.Context, token *models.EmailVerificationToken) error {
+	return implementation.InsertEmailVerificationToken(ctx, token)
+}
+
+func GetEmailVerificationTokenByHash(ctx context.Context, hash string) (*models.EmailVerificationToken, error) {
+	return implementation.GetEmailVerificationTokenByHash(ctx, hash)
+}
+
+func MarkEmailVerificationTokenUsed(ctx context.Context, id string) (bool, error) {
+	return implementation.MarkEmailVerificationTokenUsed(ctx, id)
+}
+
+func GetTOTP(ctx context.Context, userID string) (*models.TOTP, error) {
+	return implementation.GetTOTP(ctx, userID)
+}
+
+func UpsertTOTP(ctx context.Context, totp *models.TOTP) error {
+	return implementation.UpsertTOTP(ctx, totp)
+}
+
+func ConfirmTOTP(ctx context.Context, userID string) error {
+	return implementation.ConfirmTOTP(ctx, userID)
+}
+
+func DeleteTOTP(ctx context.Context, userID string) error {
+	return implementation.DeleteTOTP(ctx, userID)
+}
+
+func UseTOTPStep(ctx context.Context, userID string, step int64) (bool, error) {
+	return implementation.UseTOTPStep(ctx, userID, step)
+}
+
+func ReplaceRecoveryCodes(ctx context.Context, userID string, hashes []string) error {
+	return implementation.ReplaceRecoveryCodes(ctx, userID, hashes)
+}
+
+func UseRecoveryCode(ctx context.Context, userID string, hash string) (bool, error) {
+	return implementation.UseRecoveryCode(ctx, userID, hash)
+}
+
+func InsertExternalIdentity(ctx context.Context, identity *models.ExternalIdentity) error {
+	return implementation.InsertExternalIdentity(ctx, identity)
+}
+
+func GetExternalIdentity(ctx context.Context, provider string, subject string) (*models.ExternalIdentity, error) {
+	return implementation.GetExternalIdentity(ctx, provider, subject)
+}
+
+func InsertAPIKey(ctx context.Context, key *models.APIKey) error {
+	return implementation.InsertAPIKey(ctx, key)
+}
+
+func GetAPIKeyByHash(ctx context.Context, hash string) (*models.APIKey, error) {
+	return implementation.GetAPIKeyByHash(ctx, hash)
+}
+
+func ListAPIKeys(ctx context.Context, userID string) ([]*models.APIKey, error) {
+	return implementation.ListAPIKeys(ctx, userID)
+}
+
+func RevokeAPIKey(ctx context.Context, id string, userID string) (bool, error) {
+	return implementation.RevokeAPIKey(ctx, id, userID)
+}
+
+func TouchAPIKey(ctx context.Context, id string) error {
+	return implementation.TouchAPIKey(ctx, id)
+}
+
+func GetLoginAttempts(ctx context.Context, key string) (*models.LoginAttempts, error) {
+	return implementation.GetLoginAttempts(ctx, key)
+}
+
+func RecordLoginFailure(ctx context.Context, key string, window time.Duration) (*models.LoginAttempts, error) {
+	return implementation.RecordLoginFailure(ctx, key, window)
+}
+
+func ResetLoginAttempts(ctx context.Context, key string) error {
+	return implementation.ResetLoginAttempts(ctx, key)
+}
+
+func ReleaseLoginAttempt(ctx context.Context, key string) error {
+	return implementation.ReleaseLoginAttempt(ctx, key)
+}
+
+func InsertSession(ctx context.Context, session *models.Session) error {
+	return implementation.InsertSession(ctx, session)
+}
+
+func RefreshSession(ctx context.Context, id string, ip string, expiresAt time.Time) error {
+	return implementation.RefreshSession(ctx, id, ip, expiresAt)
+}
+
+func TouchSession(ctx context.Context, id string) error {
+	return implementation.TouchSession(ctx, id)
+}
+
+func ListSessions(ctx context.Context, userID string) ([]*models.Session, error) {
+	return implementation.ListSessions(ctx, userID)
+}
+
+func RevokeSession(ctx context.Context, id string, userID string) (bool, error) {
+	return implementation.RevokeSession(ctx, id, userID)
+}
+
+func IsSessionRevoked(ctx context.Context, id string) (bool, error) {
+	return implementation.IsSessionRevoked(ctx, id)
+}
+
+func TakeRateLimitToken(ctx context.Context, key string, capacity float64, rate float64) (float64, bool, error) {
+	return implementation.TakeRateLimitToken(ctx, key, capacity, rate)
+}
+
+func ReserveIdempotencyKey(ctx context.Context, record *models.IdempotencyRecord) (bool, error) {
+	return implementation.ReserveIdempotencyKey(ctx, record)
+}
+
+func GetIdempotencyRecord(ctx context.Context, userID string, key string) (*models.IdempotencyRecord, error) {
+	return implementation.GetIdempotencyRecord(ctx, userID, key)
+}
+
+func CompleteIdempotencyRecord(ctx context.Context, record *models.IdempotencyRecord) error {
+	return implementation.CompleteIdempotencyRecord(ctx, record)
+}
+
+func DeleteIdempotencyRecord(ctx context.Context, userID string, key string) error {
+	return implementation.DeleteIdempotencyRecord(ctx, userID, key)
+}
+
+func ListUsers(ctx context.Context, page uint64) ([]*models.User, error) {
+	return implementation.ListUsers(ctx, page)
+}
+
+func GetUserRoles(ctx context.Context, userID string) ([]string, error) {
+	return implementation.GetUserRoles(ctx, userID)
+}
+
+func GetRolePermissions(ctx context.Context, roles []string) ([]string, error) {
+	return implementation.GetRolePermissions(ctx, roles)
+}
+
+func SetUserRoles(ctx context.Context, userID string, roles []string) error {
+	return implementation.SetUserRoles(ctx, userID, roles)
+}
+
+func Ping(ctx context.Context) error {
+	return implementation.Ping(ctx)
+}
+
+func SchemaVersion(ctx context.Context) (int, error) {
+	return implementation.SchemaVersion(ctx)
+}
+
 func Close() error {
 	return implementation.Close()
 }
@@ -0,0 +1,190 @@
+package revocation
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/th3khan/rest-web-sockets-with-go/models"
+	"github.com/th3khan/rest-web-sockets-with-go/repositories"
+)
+
+type tokenEntry struct {
+	revoked   bool
+	cachedAt  time.Time
+	expiresAt time.Time
+}
+
+type sessionEntry struct {
+	revoked  bool
+	cachedAt time.Time
+}
+
+type userEntry struct {
+	version  int
+	cachedAt time.Time
+}
+
+type Store struct {
+	mutex    *sync.Mutex
+	tokens   map[string]tokenEntry
+	sessions map[string]sessionEntry
+	users    map[string]userEntry
+	ttl      time.Duration
+	pruned   time.Time
+}
+
+func NewStore(ttl time.Duration) *Store {
+	return &Store{
+		mutex:    &sync.Mutex{},
+		tokens:   make(map[string]tokenEntry),
+		sessions: make(map[string]sessionEntry),
+		users:    make(map[string]userEntry),
+		ttl:      ttl,
+		pruned:   time.Now(),
+	}
+}
+
+func (s *Store) prune(now time.Time) {
+	if now.Sub(s.pruned) < s.ttl {
+		return
+	}
+	s.pruned = now
+	for id, entry := range s.tokens {
+		if (!entry.revoked && now.Sub(entry.cachedAt) >= s.ttl) || now.After(entry.expiresAt) {
+			delete(s.tokens, id)
+		}
+	}
+	for id, entry := range s.sessions {
+		if !entry.revoked && now.Sub(entry.cachedAt) >= s.ttl {
+			delete(s.sessions, id)
+		}
+	}
+	for id, entry := range s.users {
+		if now.Sub(entry.cachedAt) >= s.ttl {
+			delete(s.users, id)
+		}
+	}
+}
+
+func (s *Store) RevokeToken(ctx context.Context, tokenID string, userID string, expiresAt time.Time) error {
+	err := repositories.InsertRevokedToken(ctx, &models.RevokedToken{
+		ID:        tokenID,
+		UserID:    userID,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.prune(now)
+	s.tokens[tokenID] = tokenEntry{revoked: true, cachedAt: now, expiresAt: expiresAt}
+	return nil
+}
+
+func (s *Store) RevokeUser(ctx context.Context, userID string) error {
+	if err := repositories.RevokeUserTokens(ctx, userID); err != nil {
+		return err
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.users, userID)
+	return nil
+}
+
+func (s *Store) RevokeSession(ctx context.Context, sessionID string, userID string) (bool, error) {
+	revoked, err := repositories.RevokeSession(ctx, sessionID, userID)
+	if err != nil || !revoked {
+		return revoked, err
+	}
+	now := time.Now()
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.prune(now)
+	s.sessions[sessionID] = sessionEntry{revoked: true, cachedAt: now}
+	return true, nil
+}
+
+func (s *Store) IsRevoked(ctx context.Context, claims *models.AppClaims) (bool, error) {
+	revoked, err := s.isTokenRevoked(ctx, claims.Id, time.Unix(claims.ExpiresAt, 0))
+	if err != nil || revoked {
+		return revoked, err
+	}
+	revoked, err = s.isSessionRevoked(ctx, claims.SessionID)
+	if err != nil || revoked {
+		return revoked, err
+	}
+	version, err := s.userTokenVersion(ctx, claims.UserID)
+	if err != nil {
+		return false, err
+	}
+	return claims.TokenVersion < version, nil
+}
+
+func (s *Store) isTokenRevoked(ctx context.Context, id string, expiresAt time.Time) (bool, error) {
+	if id == "" {
+		return false, nil
+	}
+	s.mutex.Lock()
+	entry, ok := s.tokens[id]
+	s.mutex.Unlock()
+	if ok && (entry.revoked || time.Since(entry.cachedAt) < s.ttl) {
+		return entry.revoked, nil
+	}
+
+	revoked, err := repositories.IsTokenRevoked(ctx, id)
+	if err != nil {
+		return false, err
+	}
+	now := time.Now()
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.prune(now)
+	s.tokens[id] = tokenEntry{revoked: revoked, cachedAt: now, expiresAt: expiresAt}
+	return revoked, nil
+}
+
+func (s *Store) isSessionRevoked(ctx context.Context, id string) (bool, error) {
+	if id == "" {
+		return false, nil
+	}
+	s.mutex.Lock()
+	entry, ok := s.sessions[id]
+	s.mutex.Unlock()
+	if ok && (entry.revoked || time.Since(entry.cachedAt) < s.ttl) {
+		return entry.revoked, nil
+	}
+
+	revoked, err := repositories.IsSessionRevoked(ctx, id)
+	if err != nil {
+		return false, err
+	}
+	now := time.Now()
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.prune(now)
+	s.sessions[id] = sessionEntry{revoked: revoked, cachedAt: now}
+	return revoked, nil
+}
+
+func (s *Store) userTokenVersion(ctx context.Context, userID string) (int, error) {
+	s.mutex.Lock()
+	entry, ok := s.users[userID]
+	s.mutex.Unlock()
+	if ok && time.Since(entry.cachedAt) < s.ttl {
+		return entry.version, nil
+	}
+
+	version, err := repositories.GetUserTokenVersion(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	now := time.Now()
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.prune(now)
+	s.users[userID] = userEntry{version: version, cachedAt: now}
+	return version, nil
+}
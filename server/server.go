@@ -4,30 +4,90 @@ import (
 	"context"
 	"errors"
 	"log"
+	"log/slog"
 	"net/http"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/rs/cors"
 	"github.com/th3khan/rest-web-sockets-with-go/database"
+	"github.com/th3khan/rest-web-sockets-with-go/docs"
+	"github.com/th3khan/rest-web-sockets-with-go/idempotency"
+	"github.com/th3khan/rest-web-sockets-with-go/keys"
+	"github.com/th3khan/rest-web-sockets-with-go/lockout"
+	"github.com/th3khan/rest-web-sockets-with-go/logging"
+	"github.com/th3khan/rest-web-sockets-with-go/mailer"
+	"github.com/th3khan/rest-web-sockets-with-go/metrics"
+	"github.com/th3khan/rest-web-sockets-with-go/oidc"
+	"github.com/th3khan/rest-web-sockets-with-go/passwords"
+	"github.com/th3khan/rest-web-sockets-with-go/problem"
+	"github.com/th3khan/rest-web-sockets-with-go/ratelimit"
 	"github.com/th3khan/rest-web-sockets-with-go/repositories"
+	"github.com/th3khan/rest-web-sockets-with-go/revocation"
+	"github.com/th3khan/rest-web-sockets-with-go/tracing"
 	"github.com/th3khan/rest-web-sockets-with-go/websocket"
 )
 
+const (
+	ENVIRONMENT_PRODUCTION  = "production"
+	ENVIRONMENT_DEVELOPMENT = "development"
+)
+
 type Config struct {
-	Port        string
-	JWTSecret   string
-	DataBaseUrl string
+	Environment           string
+	Port                  string
+	JWTSecret             string
+	DataBaseUrl           string
+	LogLevel              string
+	AccessTokenTTL        time.Duration
+	RefreshTokenTTL       time.Duration
+	RevocationTTL         time.Duration
+	AppBaseURL            string
+	EmailVerificationTTL  time.Duration
+	PasswordResetTTL      time.Duration
+	MFAChallengeTTL       time.Duration
+	MFAIssuer             string
+	RequireVerifiedEmail  string
+	LegacyAPIDeprecatedAt time.Time
+	LegacyAPISunsetAt     time.Time
+	Mail                  mailer.Config
+	Passwords             passwords.Config
+	Keys                  keys.Config
+	DataBase              database.MySQLConfig
+	Tracing               tracing.Config
+	OIDC                  oidc.Config
+	Lockout               lockout.Config
+	RateLimit             ratelimit.Config
+	Idempotency           idempotency.Config
 }
 
 type Server interface {
 	Config() *Config
 	Hub() *websocket.Hub
+	Logger() *slog.Logger
+	Revocations() *revocation.Store
+	Keys() *keys.Manager
+	Mailer() mailer.Mailer
+	Passwords() *passwords.Policy
+	OIDC() *oidc.Provider
+	Lockout() *lockout.Guard
+	RateLimiter() *ratelimit.Limiter
+	Idempotency() *idempotency.Cache
 }
 
 type Broker struct {
 	config *Config
 	router *mux.Router
 	hub    *websocket.Hub
+	logger *slog.Logger
+	store  *revocation.Store
+	keys   *keys.Manager
+	mailer mailer.Mailer
+	hasher *passwords.Policy
+	oidc   *oidc.Provider
+	guard  *lockout.Guard
+	limit  *ratelimit.Limiter
+	replay *idempotency.Cache
 }
 
 func (b *Broker) Config() *Config {
@@ -38,34 +98,135 @@ func NewServer(ctx context.Context, config *Config) (*Broker, error) {
 	if config.Port == "" {
 		return nil, errors.New("Port is required")
 	}
-	if config.JWTSecret == "" {
-		return nil, errors.New("Secret Key is Required")
-	}
 	if config.DataBaseUrl == "" {
 		return nil, errors.New("Database url is required")
 	}
+	if config.Environment == "" {
+		config.Environment = ENVIRONMENT_PRODUCTION
+	}
+	problem.SetExposeInternal(config.Environment == ENVIRONMENT_DEVELOPMENT)
+	if config.AccessTokenTTL <= 0 {
+		config.AccessTokenTTL = 15 * time.Minute
+	}
+	if config.RefreshTokenTTL <= 0 {
+		config.RefreshTokenTTL = 30 * 24 * time.Hour
+	}
+	if config.RevocationTTL <= 0 {
+		config.RevocationTTL = 10 * time.Second
+	}
+	config.Keys.Secret = config.JWTSecret
+	config.Keys.AllowEphemeral = config.Environment == ENVIRONMENT_DEVELOPMENT
+	if config.Keys.RetentionPeriod <= 0 {
+		config.Keys.RetentionPeriod = config.AccessTokenTTL
+	}
+	keyManager, err := keys.NewManager(&config.Keys)
+	if err != nil {
+		return nil, err
+	}
+	hasher, err := passwords.NewPolicy(&config.Passwords)
+	if err != nil {
+		return nil, err
+	}
+	if config.EmailVerificationTTL <= 0 {
+		config.EmailVerificationTTL = 24 * time.Hour
+	}
+	if config.PasswordResetTTL <= 0 {
+		config.PasswordResetTTL = time.Hour
+	}
+	if config.MFAChallengeTTL <= 0 {
+		config.MFAChallengeTTL = 5 * time.Minute
+	}
+	if !config.LegacyAPISunsetAt.IsZero() && config.LegacyAPISunsetAt.Before(config.LegacyAPIDeprecatedAt) {
+		return nil, errors.New("Legacy API sunset must not be before its deprecation")
+	}
+	if config.MFAIssuer == "" {
+		config.MFAIssuer = "rest-web-sockets-with-go"
+	}
+	var provider *oidc.Provider
+	if config.OIDC.IssuerURL != "" {
+		provider, err = oidc.NewProvider(&config.OIDC)
+		if err != nil {
+			return nil, err
+		}
+	}
+	guard, err := lockout.New(&config.Lockout)
+	if err != nil {
+		return nil, err
+	}
+	limiter, err := ratelimit.New(&config.RateLimit)
+	if err != nil {
+		return nil, err
+	}
+	replay, err := idempotency.New(&config.Idempotency)
+	if err != nil {
+		return nil, err
+	}
+	if config.Mail.Driver == "" && config.Environment == ENVIRONMENT_DEVELOPMENT {
+		config.Mail.Driver = mailer.DRIVER_LOG
+	}
+	logger := logging.New(config.LogLevel)
+	slog.SetDefault(logger)
+	mail, err := mailer.New(&config.Mail, logger)
+	if err != nil {
+		return nil, err
+	}
 	broker := &Broker{
 		config: config,
 		router: mux.NewRouter(),
-		hub:    websocket.NewHub(),
+		hub:    websocket.NewHub(logger),
+		logger: logger,
+		store:  revocation.NewStore(config.RevocationTTL),
+		keys:   keyManager,
+		mailer: mail,
+		hasher: hasher,
+		oidc:   provider,
+		guard:  guard,
+		limit:  limiter,
+		replay: replay,
 	}
 	return broker, nil
 }
 
+func (b *Broker) checkRoutes() {
+	divergences, err := docs.CheckRoutes(b.router)
+	if err != nil {
+		b.logger.Error("could not check routes against the openapi document", "error", err)
+		return
+	}
+	for _, divergence := range divergences {
+		b.logger.Warn("openapi document out of date", "route", divergence)
+	}
+}
+
 func (b *Broker) Start(binder func(s Server, r *mux.Router)) {
 	b.router = mux.NewRouter()
+	b.router.NotFoundHandler = problem.NotFoundHandler()
+	b.router.MethodNotAllowedHandler = problem.MethodNotAllowedHandler()
 	binder(b, b.router)
+	b.checkRoutes()
 
 	handler := cors.Default().Handler(b.router)
 
-	repo, err := database.NewMySQLRepository(b.config.DataBaseUrl)
+	shutdownTracing, err := tracing.Setup(context.Background(), &b.config.Tracing)
+	if err != nil {
+		log.Fatal("Error", err)
+	}
+	defer shutdownTracing(context.Background())
+
+	repo, err := database.NewMySQLRepository(context.Background(), b.config.DataBaseUrl, &b.config.DataBase)
 	if err != nil {
 		log.Fatal("Error", err)
 	}
 	go b.hub.Run()
-	repositories.SetRepository(repo)
+	go func() {
+		if err := b.keys.Run(context.Background()); err != nil {
+			b.logger.Error("key rotation stopped", "error", err)
+		}
+	}()
+	metrics.RegisterHub(b.hub)
+	repositories.SetRepository(tracing.NewTracedRepository(metrics.NewInstrumentedRepository(repo)))
 
-	log.Println("Starting server on Port", b.config.Port)
+	b.logger.Info("starting server", "port", b.config.Port)
 
 	if err := http.ListenAndServe(b.config.Port, handler); err != nil {
 		log.Fatal("ListenAndServer: ", err)
@@ -75,3 +236,39 @@ func (b *Broker) Start(binder func(s Server, r *mux.Router)) {
 func (b *Broker) Hub() *websocket.Hub {
 	return b.hub
 }
+
+func (b *Broker) Logger() *slog.Logger {
+	return b.logger
+}
+
+func (b *Broker) Revocations() *revocation.Store {
+	return b.store
+}
+
+func (b *Broker) Keys() *keys.Manager {
+	return b.keys
+}
+
+func (b *Broker) Mailer() mailer.Mailer {
+	return b.mailer
+}
+
+func (b *Broker) Passwords() *passwords.Policy {
+	return b.hasher
+}
+
+func (b *Broker) OIDC() *oidc.Provider {
+	return b.oidc
+}
+
+func (b *Broker) Lockout() *lockout.Guard {
+	return b.guard
+}
+
+func (b *Broker) RateLimiter() *ratelimit.Limiter {
+	return b.limit
+}
+
+func (b *Broker) Idempotency() *idempotency.Cache {
+	return b.replay
+}
@@ -0,0 +1,86 @@
+package totp
+
+import (
+	"testing"
+	"time"
+)
+
+const RFC_SECRET = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestCodeMatchesRFC6238Vectors(t *testing.T) {
+	vectors := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, vector := range vectors {
+		code, err := Code(RFC_SECRET, Step(time.Unix(vector.unix, 0)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if code != vector.code {
+			t.Errorf("time %d: expected %s, got %s", vector.unix, vector.code, code)
+		}
+	}
+}
+
+func TestValidateAcceptsSkew(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	current := Step(now)
+	for offset := int64(-SKEW); offset <= SKEW; offset++ {
+		code, err := Code(RFC_SECRET, current+offset)
+		if err != nil {
+			t.Fatal(err)
+		}
+		step, ok := Validate(RFC_SECRET, code, now)
+		if !ok || step != current+offset {
+			t.Errorf("offset %d: expected step %d to validate, got %d %v", offset, current+offset, step, ok)
+		}
+	}
+}
+
+func TestValidateRejectsOutsideSkew(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	current := Step(now)
+	for _, offset := range []int64{-SKEW - 1, SKEW + 1} {
+		code, err := Code(RFC_SECRET, current+offset)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := Validate(RFC_SECRET, code, now); ok {
+			t.Errorf("offset %d: expected code to be rejected", offset)
+		}
+	}
+}
+
+func TestValidateReturnsStepForReplayCheck(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	code, err := Code(RFC_SECRET, Step(now))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lastUsed := int64(0)
+	use := func(at time.Time) bool {
+		step, ok := Validate(RFC_SECRET, code, at)
+		if !ok || step <= lastUsed {
+			return false
+		}
+		lastUsed = step
+		return true
+	}
+	if !use(now) {
+		t.Fatal("expected first use to be accepted")
+	}
+	if use(now) {
+		t.Fatal("expected replay in the same step to be rejected")
+	}
+	if use(now.Add(PERIOD * time.Second)) {
+		t.Fatal("expected replay in the next step to be rejected")
+	}
+}
@@ -0,0 +1,397 @@
+package tracing
+
+import (
+	"context"
+	"time"
+
+	"github.com/th3khan/rest-web-sockets-with-go/models"
+	"github.com/th3khan/rest-web-sockets-with-go/repositories"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type TracedRepository struct {
+	next repositories.Repository
+}
+
+func NewTracedRepository(next repositories.Repository) *TracedRepository {
+	return &TracedRepository{
+		next: next,
+	}
+}
+
+func start(ctx context.Context, method string) (context.Context, trace.Span) {
+	return Tracer().Start(ctx, "repositories."+method, trace.WithSpanKind(trace.SpanKindClient))
+}
+
+func end(span trace.Span, err *error) {
+	if *err != nil {
+		span.RecordError(*err)
+		span.SetStatus(codes.Error, (*err).Error())
+	}
+	span.End()
+}
+
+func (r *TracedRepository) InsertUser(ctx context.Context, user *models.User) (err error) {
+	ctx, span := start(ctx, "InsertUser")
+	defer end(span, &err)
+	return r.next.InsertUser(ctx, user)
+}
+
+func (r *TracedRepository) GetUserById(ctx context.Context, id string) (user *models.User, err error) {
+	ctx, span := start(ctx, "GetUserById")
+	defer end(span, &err)
+	return r.next.GetUserById(ctx, id)
+}
+
+func (r *TracedRepository) GetUserByEmail(ctx context.Context, email string) (user *models.User, err error) {
+	ctx, span := start(ctx, "GetUserByEmail")
+	defer end(span, &err)
+	return r.next.GetUserByEmail(ctx, email)
+}
+
+func (r *TracedRepository) InsertPost(ctx context.Context, post *models.Post) (err error) {
+	ctx, span := start(ctx, "InsertPost")
+	defer end(span, &err)
+	return r.next.InsertPost(ctx, post)
+}
+
+func (r *TracedRepository) GetPostById(ctx context.Context, id string) (post *models.Post, err error) {
+	ctx, span := start(ctx, "GetPostById")
+	defer end(span, &err)
+	return r.next.GetPostById(ctx, id)
+}
+
+func (r *TracedRepository) UpdatePost(ctx context.Context, post *models.Post) (err error) {
+	ctx, span := start(ctx, "UpdatePost")
+	defer end(span, &err)
+	return r.next.UpdatePost(ctx, post)
+}
+
+func (r *TracedRepository) DeletePost(ctx context.Context, id string, userId string) (err error) {
+	ctx, span := start(ctx, "DeletePost")
+	defer end(span, &err)
+	return r.next.DeletePost(ctx, id, userId)
+}
+
+func (r *TracedRepository) ListPosts(ctx context.Context, page uint64) (posts []*models.Post, err error) {
+	ctx, span := start(ctx, "ListPosts")
+	defer end(span, &err)
+	return r.next.ListPosts(ctx, page)
+}
+
+func (r *TracedRepository) InsertRefreshToken(ctx context.Context, token *models.RefreshToken) (err error) {
+	ctx, span := start(ctx, "InsertRefreshToken")
+	defer end(span, &err)
+	return r.next.InsertRefreshToken(ctx, token)
+}
+
+func (r *TracedRepository) GetRefreshTokenByHash(ctx context.Context, hash string) (token *models.RefreshToken, err error) {
+	ctx, span := start(ctx, "GetRefreshTokenByHash")
+	defer end(span, &err)
+	return r.next.GetRefreshTokenByHash(ctx, hash)
+}
+
+func (r *TracedRepository) RevokeRefreshToken(ctx context.Context, id string, replacedBy string) (revoked bool, err error) {
+	ctx, span := start(ctx, "RevokeRefreshToken")
+	defer end(span, &err)
+	return r.next.RevokeRefreshToken(ctx, id, replacedBy)
+}
+
+func (r *TracedRepository) RevokeRefreshTokenFamily(ctx context.Context, familyID string) (err error) {
+	ctx, span := start(ctx, "RevokeRefreshTokenFamily")
+	defer end(span, &err)
+	return r.next.RevokeRefreshTokenFamily(ctx, familyID)
+}
+
+func (r *TracedRepository) InsertRevokedToken(ctx context.Context, token *models.RevokedToken) (err error) {
+	ctx, span := start(ctx, "InsertRevokedToken")
+	defer end(span, &err)
+	return r.next.InsertRevokedToken(ctx, token)
+}
+
+func (r *TracedRepository) IsTokenRevoked(ctx context.Context, id string) (revoked bool, err error) {
+	ctx, span := start(ctx, "IsTokenRevoked")
+	defer end(span, &err)
+	return r.next.IsTokenRevoked(ctx, id)
+}
+
+func (r *TracedRepository) RevokeUserTokens(ctx context.Context, userID string) (err error) {
+	ctx, span := start(ctx, "RevokeUserTokens")
+	defer end(span, &err)
+	return r.next.RevokeUserTokens(ctx, userID)
+}
+
+func (r *TracedRepository) GetUserTokenVersion(ctx context.Context, userID string) (version int, err error) {
+	ctx, span := start(ctx, "GetUserTokenVersion")
+	defer end(span, &err)
+	return r.next.GetUserTokenVersion(ctx, userID)
+}
+
+func (r *TracedRepository) ListUsers(ctx context.Context, page uint64) (users []*models.User, err error) {
+	ctx, span := start(ctx, "ListUsers")
+	defer end(span, &err)
+	return r.next.ListUsers(ctx, page)
+}
+
+func (r *TracedRepository) GetUserRoles(ctx context.Context, userID string) (roles []string, err error) {
+	ctx, span := start(ctx, "GetUserRoles")
+	defer end(span, &err)
+	return r.next.GetUserRoles(ctx, userID)
+}
+
+func (r *TracedRepository) GetRolePermissions(ctx context.Context, roles []string) (permissions []string, err error) {
+	ctx, span := start(ctx, "GetRolePermissions")
+	defer end(span, &err)
+	return r.next.GetRolePermissions(ctx, roles)
+}
+
+func (r *TracedRepository) SetUserRoles(ctx context.Context, userID string, roles []string) (err error) {
+	ctx, span := start(ctx, "SetUserRoles")
+	defer end(span, &err)
+	return r.next.SetUserRoles(ctx, userID, roles)
+}
+
+func (r *TracedRepository) MarkEmailVerified(ctx context.Context, userID string) (err error) {
+	ctx, span := start(ctx, "MarkEmailVerified")
+	defer end(span, &err)
+	return r.next.MarkEmailVerified(ctx, userID)
+}
+
+func (r *TracedRepository) UpdateUserPassword(ctx context.Context, userID string, password string) (err error) {
+	ctx, span := start(ctx, "UpdateUserPassword")
+	defer end(span, &err)
+	return r.next.UpdateUserPassword(ctx, userID, password)
+}
+
+func (r *TracedRepository) InsertPasswordResetToken(ctx context.Context, token *models.PasswordResetToken) (err error) {
+	ctx, span := start(ctx, "InsertPasswordResetToken")
+	defer end(span, &err)
+	return r.next.InsertPasswordResetToken(ctx, token)
+}
+
+func (r *TracedRepository) GetPasswordResetTokenByHash(ctx context.Context, hash string) (token *models.PasswordResetToken, err error) {
+	ctx, span := start(ctx, "GetPasswordResetTokenByHash")
+	defer end(span, &err)
+	return r.next.GetPasswordResetTokenByHash(ctx, hash)
+}
+
+func (r *TracedRepository) MarkPasswordResetTokenUsed(ctx context.Context, id string) (used bool, err error) {
+	ctx, span := start(ctx, "MarkPasswordResetTokenUsed")
+	defer end(span, &err)
+	return r.next.MarkPasswordResetTokenUsed(ctx, id)
+}
+
+func (r *TracedRepository) GetTOTP(ctx context.Context, userID string) (totp *models.TOTP, err error) {
+	ctx, span := start(ctx, "GetTOTP")
+	defer end(span, &err)
+	return r.next.GetTOTP(ctx, userID)
+}
+
+func (r *TracedRepository) UpsertTOTP(ctx context.Context, totp *models.TOTP) (err error) {
+	ctx, span := start(ctx, "UpsertTOTP")
+	defer end(span, &err)
+	return r.next.UpsertTOTP(ctx, totp)
+}
+
+func (r *TracedRepository) ConfirmTOTP(ctx context.Context, userID string) (err error) {
+	ctx, span := start(ctx, "ConfirmTOTP")
+	defer end(span, &err)
+	return r.next.ConfirmTOTP(ctx, userID)
+}
+
+func (r *TracedRepository) DeleteTOTP(ctx context.Context, userID string) (err error) {
+	ctx, span := start(ctx, "DeleteTOTP")
+	defer end(span, &err)
+	return r.next.DeleteTOTP(ctx, userID)
+}
+
+func (r *TracedRepository) UseTOTPStep(ctx context.Context, userID string, step int64) (used bool, err error) {
+	ctx, span := start(ctx, "UseTOTPStep")
+	defer end(span, &err)
+	return r.next.UseTOTPStep(ctx, userID, step)
+}
+
+func (r *TracedRepository) ReplaceRecoveryCodes(ctx context.Context, userID string, hashes []string) (err error) {
+	ctx, span := start(ctx, "ReplaceRecoveryCodes")
+	defer end(span, &err)
+	return r.next.ReplaceRecoveryCodes(ctx, userID, hashes)
+}
+
+func (r *TracedRepository) UseRecoveryCode(ctx context.Context, userID string, hash string) (used bool, err error) {
+	ctx, span := start(ctx, "UseRecoveryCode")
+	defer end(span, &err)
+	return r.next.UseRecoveryCode(ctx, userID, hash)
+}
+
+func (r *TracedRepository) InsertExternalIdentity(ctx context.Context, identity *models.ExternalIdentity) (err error) {
+	ctx, span := start(ctx, "InsertExternalIdentity")
+	defer end(span, &err)
+	return r.next.InsertExternalIdentity(ctx, identity)
+}
+
+func (r *TracedRepository) GetExternalIdentity(ctx context.Context, provider string, subject string) (identity *models.ExternalIdentity, err error) {
+	ctx, span := start(ctx, "GetExternalIdentity")
+	defer end(span, &err)
+	return r.next.GetExternalIdentity(ctx, provider, subject)
+}
+
+func (r *TracedRepository) InsertAPIKey(ctx context.Context, key *models.APIKey) (err error) {
+	ctx, span := start(ctx, "InsertAPIKey")
+	defer end(span, &err)
+	return r.next.InsertAPIKey(ctx, key)
+}
+
+func (r *TracedRepository) GetAPIKeyByHash(ctx context.Context, hash string) (key *models.APIKey, err error) {
+	ctx, span := start(ctx, "GetAPIKeyByHash")
+	defer end(span, &err)
+	return r.next.GetAPIKeyByHash(ctx, hash)
+}
+
+func (r *TracedRepository) ListAPIKeys(ctx context.Context, userID string) (keys []*models.APIKey, err error) {
+	ctx, span := start(ctx, "ListAPIKeys")
+	defer end(span, &err)
+	return r.next.ListAPIKeys(ctx, userID)
+}
+
+func (r *TracedRepository) RevokeAPIKey(ctx context.Context, id string, userID string) (revoked bool, err error) {
+	ctx, span := start(ctx, "RevokeAPIKey")
+	defer end(span, &err)
+	return r.next.RevokeAPIKey(ctx, id, userID)
+}
+
+func (r *TracedRepository) TouchAPIKey(ctx context.Context, id string) (err error) {
+	ctx, span := start(ctx, "TouchAPIKey")
+	defer end(span, &err)
+	return r.next.TouchAPIKey(ctx, id)
+}
+
+func (r *TracedRepository) GetLoginAttempts(ctx context.Context, key string) (attempts *models.LoginAttempts, err error) {
+	ctx, span := start(ctx, "GetLoginAttempts")
+	defer end(span, &err)
+	return r.next.GetLoginAttempts(ctx, key)
+}
+
+func (r *TracedRepository) RecordLoginFailure(ctx context.Context, key string, window time.Duration) (attempts *models.LoginAttempts, err error) {
+	ctx, span := start(ctx, "RecordLoginFailure")
+	defer end(span, &err)
+	return r.next.RecordLoginFailure(ctx, key, window)
+}
+
+func (r *TracedRepository) ResetLoginAttempts(ctx context.Context, key string) (err error) {
+	ctx, span := start(ctx, "ResetLoginAttempts")
+	defer end(span, &err)
+	return r.next.ResetLoginAttempts(ctx, key)
+}
+
+func (r *TracedRepository) ReleaseLoginAttempt(ctx context.Context, key string) (err error) {
+	ctx, span := start(ctx, "ReleaseLoginAttempt")
+	defer end(span, &err)
+	return r.next.ReleaseLoginAttempt(ctx, key)
+}
+
+func (r *TracedRepository) InsertSession(ctx context.Context, session *models.Session) (err error) {
+	ctx, span := start(ctx, "InsertSession")
+	defer end(span, &err)
+	return r.next.InsertSession(ctx, session)
+}
+
+func (r *TracedRepository) RefreshSession(ctx context.Context, id string, ip string, expiresAt time.Time) (err error) {
+	ctx, span := start(ctx, "RefreshSession")
+	defer end(span, &err)
+	return r.next.RefreshSession(ctx, id, ip, expiresAt)
+}
+
+func (r *TracedRepository) TouchSession(ctx context.Context, id string) (err error) {
+	ctx, span := start(ctx, "TouchSession")
+	defer end(span, &err)
+	return r.next.TouchSession(ctx, id)
+}
+
+func (r *TracedRepository) ListSessions(ctx context.Context, userID string) (sessions []*models.Session, err error) {
+	ctx, span := start(ctx, "ListSessions")
+	defer end(span, &err)
+	return r.next.ListSessions(ctx, userID)
+}
+
+func (r *TracedRepository) RevokeSession(ctx context.Context, id string, userID string) (revoked bool, err error) {
+	ctx, span := start(ctx, "RevokeSession")
+	defer end(span, &err)
+	return r.next.RevokeSession(ctx, id, userID)
+}
+
+func (r *TracedRepository) IsSessionRevoked(ctx context.Context, id string) (revoked bool, err error) {
+	ctx, span := start(ctx, "IsSessionRevoked")
+	defer end(span, &err)
+	return r.next.IsSessionRevoked(ctx, id)
+}
+
+func (r *TracedRepository) TakeRateLimitToken(ctx context.Context, key string, capacity float64, rate float64) (tokens float64, allowed bool, err error) {
+	ctx, span := start(ctx, "TakeRateLimitToken")
+	defer end(span, &err)
+	return r.next.TakeRateLimitToken(ctx, key, capacity, rate)
+}
+
+func (r *TracedRepository) ReserveIdempotencyKey(ctx context.Context, record *models.IdempotencyRecord) (reserved bool, err error) {
+	ctx, span := start(ctx, "ReserveIdempotencyKey")
+	defer end(span, &err)
+	return r.next.ReserveIdempotencyKey(ctx, record)
+}
+
+func (r *TracedRepository) GetIdempotencyRecord(ctx context.Context, userID string, key string) (record *models.IdempotencyRecord, err error) {
+	ctx, span := start(ctx, "GetIdempotencyRecord")
+	defer end(span, &err)
+	return r.next.GetIdempotencyRecord(ctx, userID, key)
+}
+
+func (r *TracedRepository) CompleteIdempotencyRecord(ctx context.Context, record *models.IdempotencyRecord) (err error) {
+	ctx, span := start(ctx, "CompleteIdempotencyRecord")
+	defer end(span, &err)
+	return r.next.CompleteIdempotencyRecord(ctx, record)
+}
+
+func (r *TracedRepository) DeleteIdempotencyRecord(ctx context.Context, userID string, key string) (err error) {
+	ctx, span := start(ctx, "DeleteIdempotencyRecord")
+	defer end(span, &err)
+	return r.next.DeleteIdempotencyRecord(ctx, userID, key)
+}
+
+func (r *TracedRepository) InsertEmailVerificationToken(ctx context.Context, token *models.EmailVerificationToken) (err error) {
+	ctx, span := start(ctx, "InsertEmailVerificationToken")
+	defer end(span, &err)
+	return r.next.InsertEmailVerificationToken(ctx, token)
+}
+
+func (r *TracedRepository) GetEmailVerificationTokenByHash(ctx context.Context, hash string) (token *models.EmailVerificationToken, err error) {
+	ctx, span := start(ctx, "GetEmailVerificationTokenByHash")
+	defer end(span, &err)
+	return r.next.GetEmailVerificationTokenByHash(ctx, hash)
+}
+
+func (r *TracedRepository) MarkEmailVerificationTokenUsed(ctx context.Context, id string) (used bool, err error) {
+	ctx, span := start(ctx, "MarkEmailVerificationTokenUsed")
+	defer end(span, &err)
+	return r.next.MarkEmailVerificationTokenUsed(ctx, id)
+}
+
+func (r *TracedRepository) InvalidatePasswordResetTokens(ctx context.Context, userID string) (err error) {
+	ctx, span := start(ctx, "InvalidatePasswordResetTokens")
+	defer end(span, &err)
+	return r.next.InvalidatePasswordResetTokens(ctx, userID)
+}
+
+func (r *TracedRepository) Ping(ctx context.Context) (err error) {
+	ctx, span := start(ctx, "Ping")
+	defer end(span, &err)
+	return r.next.Ping(ctx)
+}
+
+func (r *TracedRepository) SchemaVersion(ctx context.Context) (version int, err error) {
+	ctx, span := start(ctx, "SchemaVersion")
+	defer end(span, &err)
+	return r.next.SchemaVersion(ctx)
+}
+
+func (r *TracedRepository) Close() error {
+	return r.next.Close()
+}
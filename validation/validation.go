@@ -0,0 +1,51 @@
+package validation
+
+import (
+	"net/mail"
+	"strings"
+)
+
+type Errors map[string]string
+
+func (e Errors) Add(field string, message string) {
+	if _, exists := e[field]; !exists {
+		e[field] = message
+	}
+}
+
+func (e Errors) Empty() bool {
+	return len(e) == 0
+}
+
+func (e Errors) Error() string {
+	messages := make([]string, 0, len(e))
+	for field, message := range e {
+		messages = append(messages, field+": "+message)
+	}
+	return strings.Join(messages, "; ")
+}
+
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+func ValidateEmail(errs Errors, field string, email string) {
+	if email == "" {
+		errs.Add(field, "is required")
+		return
+	}
+	if len(email) > 255 {
+		errs.Add(field, "must be at most 255 characters")
+		return
+	}
+	address, err := mail.ParseAddress(email)
+	if err != nil || address.Address != email || !strings.Contains(email[strings.LastIndex(email, "@"):], ".") {
+		errs.Add(field, "must be a valid email address")
+	}
+}
+
+func Required(errs Errors, field string, value string) {
+	if strings.TrimSpace(value) == "" {
+		errs.Add(field, "is required")
+	}
+}
@@ -1,19 +1,38 @@
 package websocket
 
-import "github.com/gorilla/websocket"
+import (
+	"github.com/gorilla/websocket"
+	"github.com/th3khan/rest-web-sockets-with-go/events"
+)
+
+const (
+	OUTBOUND_BUFFER_SIZE = 256
+)
 
 type Client struct {
-	hub      *Hub
-	id       string
-	socket   *websocket.Conn
-	outbound chan []byte
+	hub           *Hub
+	id            string
+	userID        string
+	tokenID       string
+	sessionID     string
+	socket        *websocket.Conn
+	outbound      chan []byte
+	subscriptions map[string]bool
 }
 
-func NewClient(hub *Hub, socket *websocket.Conn) *Client {
+func NewClient(hub *Hub, socket *websocket.Conn, userID string, tokenID string, sessionID string) *Client {
+	subscriptions := map[string]bool{}
+	for _, definition := range events.Definitions() {
+		subscriptions[definition.Type] = true
+	}
 	return &Client{
-		hub:      hub,
-		socket:   socket,
-		outbound: make(chan []byte),
+		hub:           hub,
+		userID:        userID,
+		tokenID:       tokenID,
+		sessionID:     sessionID,
+		socket:        socket,
+		outbound:      make(chan []byte, OUTBOUND_BUFFER_SIZE),
+		subscriptions: subscriptions,
 	}
 }
 
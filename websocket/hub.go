@@ -1,12 +1,20 @@
 package websocket
 
 import (
+	"context"
 	"encoding/json"
-	"log"
+	"log/slog"
 	"net/http"
 	"sync"
 
 	"github.com/gorilla/websocket"
+	"github.com/th3khan/rest-web-sockets-with-go/auth"
+	"github.com/th3khan/rest-web-sockets-with-go/logging"
+	"github.com/th3khan/rest-web-sockets-with-go/models"
+	"github.com/th3khan/rest-web-sockets-with-go/problem"
+	"github.com/th3khan/rest-web-sockets-with-go/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 var upgrader = websocket.Upgrader{
@@ -20,31 +28,49 @@ type Hub struct {
 	register   chan *Client
 	unregister chan *Client
 	mutex      *sync.Mutex
+	logger     *slog.Logger
+	running    bool
+	dropped    uint64
 }
 
-func NewHub() *Hub {
+func NewHub(logger *slog.Logger) *Hub {
 	return &Hub{
 		clients:    make([]*Client, 0),
 		register:   make(chan *Client),
 		unregister: make(chan *Client),
 		mutex:      &sync.Mutex{},
+		logger:     logger,
 	}
 }
 
 func (hub *Hub) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
+	info := logging.RequestInfoFromContext(r.Context())
+	principal, ok := auth.PrincipalFromContext(r.Context())
+	if !ok {
+		problem.Error(w, r, http.StatusUnauthorized, problem.CODE_UNAUTHORIZED, "authentication required")
+		return
+	}
 	socket, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
-		log.Println(err)
-		http.Error(w, "Cold not open websocket connection", http.StatusInternalServerError)
+		hub.logger.Error("could not upgrade websocket connection", "request_id", info.ID, "user_id", principal.UserID, "error", err)
 		return
 	}
-	client := NewClient(hub, socket)
+	client := NewClient(hub, socket, principal.UserID, principal.TokenID, principal.SessionID)
 	hub.register <- client
 
 	go client.Write()
 }
 
 func (hub *Hub) Run() {
+	hub.mutex.Lock()
+	hub.running = true
+	hub.mutex.Unlock()
+	defer func() {
+		hub.mutex.Lock()
+		hub.running = false
+		hub.mutex.Unlock()
+	}()
+
 	for {
 		select {
 		case client := <-hub.register:
@@ -55,18 +81,23 @@ func (hub *Hub) Run() {
 	}
 }
 
-func (hub *Hub) onConnect(client *Client) {
-	log.Println("New Client connected", client.socket.RemoteAddr())
+func (hub *Hub) Running() bool {
+	hub.mutex.Lock()
+	defer hub.mutex.Unlock()
+	return hub.running
+}
 
+func (hub *Hub) onConnect(client *Client) {
 	hub.mutex.Lock()
 	defer hub.mutex.Unlock()
 	client.id = client.socket.RemoteAddr().String()
+	hub.logger.Info("client connected", "client_id", client.id, "user_id", client.userID)
 	hub.clients = append(hub.clients, client)
 }
 
 func (hub *Hub) onDisconnect(client *Client) {
-	log.Println("Client disconnected", client.socket.RemoteAddr())
-	client.socket.Close()
+	hub.logger.Info("client disconnected", "client_id", client.id, "user_id", client.userID)
+	defer client.socket.Close()
 	hub.mutex.Lock()
 	defer hub.mutex.Unlock()
 
@@ -77,17 +108,104 @@ func (hub *Hub) onDisconnect(client *Client) {
 			break
 		}
 	}
+	if i == -1 {
+		return
+	}
 
 	copy(hub.clients[i:], hub.clients[i+1:])
 	hub.clients[len(hub.clients)-1] = nil
 	hub.clients = hub.clients[:len(hub.clients)-1]
+	close(client.outbound)
+}
+
+func (hub *Hub) DisconnectUser(userID string) {
+	hub.disconnect(func(client *Client) bool {
+		return client.userID == userID
+	})
 }
 
-func (hub *Hub) Broadcast(message interface{}, ignore *Client) {
+func (hub *Hub) DisconnectToken(tokenID string) {
+	hub.disconnect(func(client *Client) bool {
+		return client.tokenID == tokenID
+	})
+}
+
+func (hub *Hub) DisconnectSession(sessionID string) {
+	hub.disconnect(func(client *Client) bool {
+		return client.sessionID == sessionID
+	})
+}
+
+func (hub *Hub) disconnect(match func(client *Client) bool) {
+	hub.mutex.Lock()
+	var matched []*Client
+	for _, client := range hub.clients {
+		if match(client) {
+			matched = append(matched, client)
+		}
+	}
+	hub.mutex.Unlock()
+
+	for _, client := range matched {
+		hub.unregister <- client
+	}
+}
+
+func (hub *Hub) Broadcast(ctx context.Context, message interface{}, ignore *Client) {
+	_, span := tracing.Tracer().Start(context.Background(), "websocket.broadcast",
+		trace.WithLinks(trace.LinkFromContext(ctx)),
+	)
+	defer span.End()
+
+	eventType := ""
+	if m, ok := message.(models.WebsocketMessage); ok {
+		eventType = m.Type
+	}
 	data, _ := json.Marshal(message)
+	hub.mutex.Lock()
+	defer hub.mutex.Unlock()
+	span.SetAttributes(attribute.Int("websocket.clients", len(hub.clients)))
 	for _, client := range hub.clients {
-		if client != ignore {
-			client.outbound <- data
+		if client == ignore || (eventType != "" && !client.subscriptions[eventType]) {
+			continue
+		}
+		select {
+		case client.outbound <- data:
+		default:
+			hub.dropped++
+			hub.logger.Warn("dropping message for slow client", "client_id", client.id, "user_id", client.userID)
 		}
 	}
 }
+
+func (hub *Hub) ClientCount() int {
+	hub.mutex.Lock()
+	defer hub.mutex.Unlock()
+	return len(hub.clients)
+}
+
+func (hub *Hub) SubscriptionCount() int {
+	hub.mutex.Lock()
+	defer hub.mutex.Unlock()
+	count := 0
+	for _, client := range hub.clients {
+		count += len(client.subscriptions)
+	}
+	return count
+}
+
+func (hub *Hub) QueueDepth() int {
+	hub.mutex.Lock()
+	defer hub.mutex.Unlock()
+	depth := 0
+	for _, client := range hub.clients {
+		depth += len(client.outbound)
+	}
+	return depth
+}
+
+func (hub *Hub) DroppedMessages() uint64 {
+	hub.mutex.Lock()
+	defer hub.mutex.Unlock()
+	return hub.dropped
+}
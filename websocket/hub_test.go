@@ -0,0 +1,30 @@
+package websocket
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/th3khan/rest-web-sockets-with-go/events"
+	"github.com/th3khan/rest-web-sockets-with-go/models"
+)
+
+func TestBroadcastSkipsUnsubscribedClients(t *testing.T) {
+	hub := NewHub(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	subscribed := NewClient(hub, nil, "subscribed", "", "")
+	unsubscribed := NewClient(hub, nil, "unsubscribed", "", "")
+	delete(unsubscribed.subscriptions, events.EVENT_POST_CREATED)
+	hub.clients = append(hub.clients, subscribed, unsubscribed)
+
+	if count, expected := hub.SubscriptionCount(), len(events.Definitions())*2-1; count != expected {
+		t.Fatalf("expected %d subscriptions, got %d", expected, count)
+	}
+	hub.Broadcast(context.Background(), events.Message(events.NewPostCreated(&models.Post{ID: "post"})), nil)
+	if len(subscribed.outbound) != 1 {
+		t.Fatalf("expected subscribed client to receive the event, got %d messages", len(subscribed.outbound))
+	}
+	if len(unsubscribed.outbound) != 0 {
+		t.Fatalf("expected unsubscribed client to be skipped, got %d messages", len(unsubscribed.outbound))
+	}
+}